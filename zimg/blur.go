@@ -10,34 +10,55 @@ import (
 
 // BlurGaussian blurs an image!
 func BlurGaussian(inputImg *image.RGBA, radius int) *image.RGBA {
+	return BlurImage(inputImg, zmath.NewBlur(radius))
+}
+
+// BlurImage returns a blurred copy of an image
+func BlurImage(inputImg *image.RGBA, b zmath.Blur) *image.RGBA {
 	var (
-		width  int = inputImg.Bounds().Dx()
-		height int = inputImg.Bounds().Dy()
+		width  = inputImg.Bounds().Dx()
+		height = inputImg.Bounds().Dy()
+		min    = inputImg.Bounds().Min
+		chans  [4]zmath.Map
 	)
-	outputImg := image.NewRGBA(image.Rect(0, 0, width, height))
-
-	// For the loop
-	circle := zmath.GetCircleCoords(radius)
-	bounds := zmath.RI(zmath.ZVI, zmath.VI(width, height))
-
+	for i := range chans {
+		chans[i] = zmath.NewMap(zmath.VI(width, height), 0)
+	}
 	for x := 0; x < width; x++ {
 		for y := 0; y < height; y++ {
-			blurred := FuncBlurGaussian(
-				inputImg,
-				bounds,
-				zmath.VI(x, y),
-				circle,
-				float64(radius)/3.0,
-			)
-			outputImg.Set(x, y, blurred)
+			c := inputImg.RGBAAt(min.X+x, min.Y+y)
+			chans[R][x][y] = float64(c.R)
+			chans[G][x][y] = float64(c.G)
+			chans[B][x][y] = float64(c.B)
+			chans[A][x][y] = float64(c.A)
+		}
+	}
 
+	outputImg := image.NewRGBA(image.Rect(0, 0, width, height))
+	for _, m := range chans {
+		m.Blur(b)
+	}
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			outputImg.SetRGBA(x, y, color.RGBA{
+				R: toChannel(chans[R][x][y]),
+				G: toChannel(chans[G][x][y]),
+				B: toChannel(chans[B][x][y]),
+				A: toChannel(chans[A][x][y]),
+			})
 		}
 	}
 
 	return outputImg
 }
 
-// FuncBlurGaussian will blur just a single pixel of an image Gaussly
+// toChannel rounds a color value to the nearest uint8
+func toChannel(v float64) uint8 {
+	return uint8(zmath.MinMax(0, 255, math.Round(v)))
+}
+
+// FuncBlurGaussian will blur just a single pixel of an image Gaussly. To blur a whole image, BlurGaussian is
+// much faster.
 func FuncBlurGaussian(img *image.RGBA, rect *zmath.RectInt, pos zmath.VecInt, circle []zmath.VecInt, sigma float64) color.RGBA {
 	var r, g, b float64
 	var weightSum, trueWeightSum float64
@@ -45,7 +66,7 @@ func FuncBlurGaussian(img *image.RGBA, rect *zmath.RectInt, pos zmath.VecInt, ci
 
 	for _, cpt := range circle {
 		testPos := pos.Add(cpt)
-		weight := math.Pow(math.E, -1.0*float64(cpt.X*cpt.X+cpt.Y*cpt.Y)/(2*sigma*sigma))
+		weight := math.Exp(-1.0 * float64(cpt.X*cpt.X+cpt.Y*cpt.Y) / (2 * sigma * sigma))
 		trueWeightSum += weight
 		if rect.Contains(testPos) {
 			// weight summation
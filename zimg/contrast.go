@@ -19,15 +19,18 @@ func GetContrast(inputImg *image.RGBA, radius int) *image.RGBA {
 	// For the loop
 	circle := zmath.GetCircleCoords(radius)
 	bounds := zmath.NewBoxInt(0, 0, width, height)
+	sigma := float64(radius) / 3.0
+	weights := gaussWeights(circle, sigma)
 
 	for x := 0; x < width; x++ {
 		for y := 0; y < height; y++ {
-			contrast := FuncContrastGaussian(
+			contrast := contrastGaussian(
 				inputImg,
 				bounds,
 				zmath.VI(x, y),
 				circle,
-				float64(radius)/3.0,
+				weights,
+				sigma,
 			)
 
 			RGBVal := uint8(int((1.0 - contrast) * 255.0)) // probably don't need double-cast
@@ -45,18 +48,29 @@ func GetContrast(inputImg *image.RGBA, radius int) *image.RGBA {
 	return outputImg
 }
 
-// TODO: make a gauss.go file that has a function that calculates the gaussian weights of a given circle
-// so that it need only be done ONCE. (The method below is unnecessarily slow)
+// gaussWeights returns the gaussian weight of every point in the circle, so that it need only be done ONCE
+func gaussWeights(circle []zmath.VecInt, sigma float64) []float64 {
+	weights := make([]float64, len(circle))
+	for i, cpt := range circle {
+		weights[i] = math.Exp(-1.0 * float64(cpt.X*cpt.X+cpt.Y*cpt.Y) / (2 * sigma * sigma))
+	}
+	return weights
+}
 
 // FuncContrastGaussian returns the gaussian blur for the given point
 func FuncContrastGaussian(img *image.RGBA, bounds zmath.BoxInt, pos zmath.VecInt, circle []zmath.VecInt, sigma float64) float64 {
+	return contrastGaussian(img, bounds, pos, circle, gaussWeights(circle, sigma), sigma)
+}
+
+// contrastGaussian is FuncContrastGaussian, with the weight of each point of the circle already calculated
+func contrastGaussian(img *image.RGBA, bounds zmath.BoxInt, pos zmath.VecInt, circle []zmath.VecInt, weights []float64, sigma float64) float64 {
 	var pxColor = img.RGBAAt(pos.X, pos.Y)
 
 	var pixelSum float64 = 0
 	var weightSum float64 = 0
 	var gaussCoeff float64 = 1.0 / (2.0 * math.Pi * sigma * sigma)
 
-	for _, cpt := range circle {
+	for i, cpt := range circle {
 		testPos := pos.Add(cpt)
 		if zmath.IsWithinBounds(testPos, bounds) && cpt != zmath.ZVI {
 			// increment pixel counter
@@ -71,8 +85,7 @@ func FuncContrastGaussian(img *image.RGBA, bounds zmath.BoxInt, pos zmath.VecInt
 			dCol /= 3.0
 
 			// weight calculations
-			weight := math.Pow(math.E, -1.0*float64(cpt.X*cpt.X+cpt.Y*cpt.Y)/(2*sigma*sigma))
-			weightSum += weight * dCol
+			weightSum += weights[i] * dCol
 		}
 	}
 
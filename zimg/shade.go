@@ -0,0 +1,144 @@
+package zimg
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// These functions light a heightmap, returning Maps of brightness within [0, 1] that can be multiplied onto a
+// Colorify'd image with Shade. Heights are multiplied by zScale first, in the same units as the distance
+// between pixels, so a Map within [0, 1] needs a large zScale to look like anything but a plain.
+
+// Hillshade returns a NEW Map of how brightly the sun lights each point of the heightmap, from 0 where the
+// ground faces away from the sun to 1 where it faces straight at it. The sun shines from the direction of
+// azimuth, in radians measured the same way as Map.GradientAt, at altitude radians above the horizon.
+func Hillshade(heights zmath.Map, azimuth, altitude, zScale float64) zmath.Map {
+	light := [3]float64{
+		math.Cos(altitude) * math.Cos(azimuth),
+		math.Cos(altitude) * math.Sin(azimuth),
+		math.Sin(altitude),
+	}
+	return shadeEach(heights, func(pos zmath.VecInt) float64 {
+		n := normalAt(heights, pos, zScale)
+		return math.Max(0, n[0]*light[0]+n[1]*light[1]+n[2]*light[2])
+	})
+}
+
+// SlopeShade returns a NEW Map that is 1 on flat ground and darkens toward 0 as the ground gets steeper
+func SlopeShade(heights zmath.Map, zScale float64) zmath.Map {
+	return shadeEach(heights, func(pos zmath.VecInt) float64 {
+		return normalAt(heights, pos, zScale)[2]
+	})
+}
+
+// aoDirections is how many directions AmbientOcclusion looks for the horizon in
+const aoDirections = 16
+
+// AmbientOcclusion returns a NEW Map of how much of the sky each point of the heightmap can see, from 0 at the
+// bottom of a deep pit to 1 on a peak or plain. The horizon is searched for up to radius pixels away.
+func AmbientOcclusion(heights zmath.Map, zScale float64, radius int) zmath.Map {
+	var dirs [aoDirections]zmath.Vec
+	for i := range dirs {
+		angle := float64(i) * 2 * math.Pi / aoDirections
+		dirs[i] = zmath.V(math.Cos(angle), math.Sin(angle))
+	}
+
+	return shadeEach(heights, func(pos zmath.VecInt) float64 {
+		var (
+			base     = heights[pos.X][pos.Y] * zScale
+			occluded = 0.0
+		)
+		for _, dir := range dirs {
+			// the horizon is the highest angle up to any point along the way
+			horizon := 0.0
+			for dist := 1; dist <= radius; dist++ {
+				pt := zmath.VI(
+					pos.X+int(math.Round(dir.X*float64(dist))),
+					pos.Y+int(math.Round(dir.Y*float64(dist))),
+				)
+				if !heights.ContainsCoord(pt) {
+					break
+				}
+				rise := heights[pt.X][pt.Y]*zScale - base
+				horizon = math.Max(horizon, rise/math.Hypot(float64(dist), rise))
+			}
+			occluded += horizon
+		}
+		return 1 - occluded/aoDirections
+	})
+}
+
+// NormalMap returns a tangent-space normal map of the heightmap, with red pointing along +x, green pointing up
+// the image (toward -y, as in OpenGL) and blue pointing out of the ground
+func NormalMap(heights zmath.Map, zScale float64) *image.RGBA {
+	bounds := heights.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, bounds.X, bounds.Y))
+	zmath.ParallelFor(bounds.X, func(x int) {
+		for y := 0; y < bounds.Y; y++ {
+			n := normalAt(heights, zmath.VI(x, y), zScale)
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8(math.Round((n[0] + 1) * 127.5)),
+				G: uint8(math.Round((-n[1] + 1) * 127.5)),
+				B: uint8(math.Round((n[2] + 1) * 127.5)),
+				A: 255,
+			})
+		}
+	})
+	return img
+}
+
+// Shade multiplies the color of every pixel of the image by the shading at the same point, and returns the
+// image. Shade can be called several times, e.g. once with AmbientOcclusion and again with Hillshade.
+func Shade(img *image.RGBA, shading zmath.Map) *image.RGBA {
+	bounds := shading.Bounds()
+	zmath.ParallelFor(bounds.X, func(x int) {
+		for y := 0; y < bounds.Y; y++ {
+			var (
+				c = img.RGBAAt(x, y)
+				s = zmath.MinMax(0, 1, shading[x][y])
+			)
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8(float64(c.R) * s),
+				G: uint8(float64(c.G) * s),
+				B: uint8(float64(c.B) * s),
+				A: c.A,
+			})
+		}
+	})
+	return img
+}
+
+// Shade multiplies the red, green and blue of every pixel by the shading at the same point
+func (zi *ZImage) Shade(shading zmath.Map) *ZImage {
+	for _, c := range ColorsRGB {
+		for x, col := range zi.RGBA256[c] {
+			for y := range col {
+				col[y] *= zmath.MinMax(0, 1, shading[x][y])
+			}
+		}
+	}
+	return zi
+}
+
+// normalAt returns the unit normal of the heightmap at pos
+func normalAt(heights zmath.Map, pos zmath.VecInt, zScale float64) [3]float64 {
+	var (
+		d      = heights.DerivativeAt(pos).Scale(zScale)
+		length = math.Sqrt(d.X*d.X + d.Y*d.Y + 1)
+	)
+	return [3]float64{-d.X / length, -d.Y / length, 1 / length}
+}
+
+// shadeEach returns a NEW Map of shadeFunc at every point of the heightmap
+func shadeEach(heights zmath.Map, shadeFunc func(pos zmath.VecInt) float64) zmath.Map {
+	shading := zmath.NewMap(heights.Bounds(), 0)
+	zmath.ParallelFor(len(shading), func(x int) {
+		for y := range shading[x] {
+			shading[x][y] = shadeFunc(zmath.VI(x, y))
+		}
+	})
+	return shading
+}
@@ -115,10 +115,72 @@ func (zi *ZImage) Set(pos zmath.VecInt, col color.RGBA) *ZImage {
 	return zi
 }
 
-// ScaleDim scales the ZImage proportionally by the provided factor
+// ScaleDim scales the ZImage proportionally by the provided factor, like Map.ScaleDim
 func (zi *ZImage) ScaleDim(by float64) *ZImage {
+	return zi.reshape(func(m zmath.Map) zmath.Map {
+		return m.ScaleDim(by)
+	})
+}
+
+// Resize resamples the ZImage to the passed bounds with the passed Filter, like Map.Resize. The sharper filters
+// can overshoot near hard edges, so colors are clamped back within [0, 255].
+func (zi *ZImage) Resize(bounds zmath.VecInt, filter zmath.Filter) *ZImage {
+	return zi.reshape(func(m zmath.Map) zmath.Map {
+		return m.Resize(bounds, filter).SetMin(0).SetMax(255)
+	})
+}
+
+// RotateCW90 rotates the ZImage 90 degrees clockwise
+func (zi *ZImage) RotateCW90() *ZImage {
+	return zi.reshape(zmath.Map.RotateCW90)
+}
+
+// RotateCCW90 rotates the ZImage 90 degrees counterclockwise
+func (zi *ZImage) RotateCCW90() *ZImage {
+	return zi.reshape(zmath.Map.RotateCCW90)
+}
+
+// Rotate180 rotates the ZImage 180 degrees
+func (zi *ZImage) Rotate180() *ZImage {
+	return zi.reshape(zmath.Map.Rotate180)
+}
+
+// Rotate rotates the ZImage about its center by angle radians clockwise, like Map.Rotate. With
+// EdgeRenormalize, whatever nothing rotates into is left transparent.
+func (zi *ZImage) Rotate(angle float64, filter zmath.Filter, edge zmath.EdgeMode) *ZImage {
+	return zi.reshape(func(m zmath.Map) zmath.Map {
+		return m.Rotate(angle, filter, edge).SetMin(0).SetMax(255)
+	})
+}
+
+// Warp moves every pixel of the ZImage to wherever the Transform moves it, into an image of the passed bounds,
+// like Map.Warp. With EdgeRenormalize, whatever nothing moves into is left transparent.
+func (zi *ZImage) Warp(t zmath.Transform, bounds zmath.VecInt, filter zmath.Filter, edge zmath.EdgeMode) *ZImage {
+	return zi.reshape(func(m zmath.Map) zmath.Map {
+		return m.Warp(t, bounds, filter, edge).SetMin(0).SetMax(255)
+	})
+}
+
+// Crop cuts the ZImage down to the part of it from min, inclusive, to max, exclusive, like Map.Crop. If none of
+// that lies within the ZImage, it's left empty.
+func (zi *ZImage) Crop(min, max zmath.VecInt) *ZImage {
+	return zi.reshape(func(m zmath.Map) zmath.Map {
+		return m.Crop(min, max)
+	})
+}
+
+// Pad adds pixels around the edges of the ZImage, like Map.Pad. With EdgeRenormalize, they're transparent.
+func (zi *ZImage) Pad(before, after zmath.VecInt, edge zmath.EdgeMode) *ZImage {
+	return zi.reshape(func(m zmath.Map) zmath.Map {
+		return m.Pad(before, after, edge)
+	})
+}
+
+// reshape replaces every color of the ZImage with reshapeFunc of it, all of which must have the same bounds,
+// and then remakes the underlying 32-bit representation to match
+func (zi *ZImage) reshape(reshapeFunc func(zmath.Map) zmath.Map) *ZImage {
 	for i, m := range zi.RGBA256 {
-		zi.RGBA256[i] = m.ScaleDim(by)
+		zi.RGBA256[i] = reshapeFunc(m)
 	}
 	zi.RGBA32 = image.NewRGBA(image.Rect(0, 0, zi.Bounds().X, zi.Bounds().Y))
 	zi.Update()
@@ -161,14 +223,57 @@ func (zi *ZImage) MakeOpaque() *ZImage {
 	return zi
 }
 
-// BlurGaussian blurs gaussianly! (and very slowly, unfortunately)
+// BlurGaussian blurs gaussianly!
 func (zi *ZImage) BlurGaussian(radius int) *ZImage {
+	return zi.Blur(zmath.NewBlur(radius))
+}
+
+// Blur blurs every color of the ZImage with the passed Blur
+func (zi *ZImage) Blur(b zmath.Blur) *ZImage {
 	for _, m := range zi.RGBA256 {
-		m.BlurGaussian(radius)
+		m.Blur(b)
 	}
 	return zi
 }
 
+// Filter replaces the colors of the ZImage one at a time with filter of them, e.g. Map.Sobel or Map.Median,
+// clamped back within [0, 255]. By default, only red, green and blue are filtered, but if arguments are
+// provided, then only the specified ColorTypes are filtered. If the filter changes the bounds, like
+// Map.RotateCW90, the rest of the colors are filtered too, so that they all keep the same bounds.
+func (zi *ZImage) Filter(filter func(zmath.Map) zmath.Map, onColors ...ColorType) *ZImage {
+	if len(onColors) == 0 {
+		onColors = ColorsRGB[:]
+	}
+	var (
+		old      = zi.Bounds()
+		filtered [len(ColorsAll)]bool
+	)
+	for _, c := range onColors {
+		zi.RGBA256[c] = filter(zi.RGBA256[c]).SetMin(0).SetMax(255)
+		filtered[c] = true
+	}
+
+	bounds := zi.RGBA256[onColors[0]].Bounds()
+	if bounds == old {
+		return zi
+	}
+	for _, c := range ColorsAll {
+		if !filtered[c] {
+			zi.RGBA256[c] = filter(zi.RGBA256[c]).SetMin(0).SetMax(255)
+		}
+	}
+	zi.RGBA32 = image.NewRGBA(image.Rect(0, 0, bounds.X, bounds.Y))
+	return zi.Update()
+}
+
+// Convolve convolves the colors of the ZImage with the kernel, like Map.Convolve. By default, only red, green
+// and blue are convolved, but if arguments are provided, then only the specified ColorTypes are convolved.
+func (zi *ZImage) Convolve(kernel zmath.Map, edge zmath.EdgeMode, onColors ...ColorType) *ZImage {
+	return zi.Filter(func(m zmath.Map) zmath.Map {
+		return m.Convolve(kernel, edge)
+	}, onColors...)
+}
+
 // Bounds returns the bounds of the ZImage!
 func (zi *ZImage) Bounds() zmath.VecInt {
 	return zi.RGBA256[Red].Bounds()
@@ -0,0 +1,231 @@
+package zmath
+
+import "math"
+
+// EdgeMode is how filters treat points past the edges of a Map
+type EdgeMode int
+
+// Edge modes
+const (
+	EdgeRenormalize EdgeMode = iota // points past the edge are left out, and the rest are weighted up to make up for them
+	EdgeClamp                       // points past the edge take the value of the nearest point on the edge
+	EdgeWrap                        // the Map repeats past its edges
+	EdgeMirror                      // the Map is reflected across its edges
+)
+
+// edgeIndex returns which index of a line of length n stands in for index i, which may be past either end.
+// EdgeRenormalize has no stand-in for points past the edge, so it returns -1 for them.
+func edgeIndex(i, n int, edge EdgeMode) int {
+	if i >= 0 && i < n {
+		return i
+	}
+	switch edge {
+	case EdgeClamp:
+		return MinMaxInt(0, n-1, i)
+	case EdgeWrap:
+		i %= n
+		if i < 0 {
+			i += n
+		}
+		return i
+	case EdgeMirror:
+		if n == 1 {
+			return 0
+		}
+		// reflect without repeating the edge itself: ... 2 1 [0 1 2 ... n-1] n-2 n-3 ...
+		period := 2 * (n - 1)
+		i %= period
+		if i < 0 {
+			i += period
+		}
+		if i >= n {
+			i = period - i
+		}
+		return i
+	default:
+		return -1
+	}
+}
+
+// Blur is a Gaussian blur
+type Blur struct {
+	Sigma float64  // the standard deviation of the Gaussian, in pixels
+	Edge  EdgeMode // how to treat points past the edges
+	Fast  bool     // approximate the Gaussian with three box blurs, which take just as long no matter how large Sigma is
+}
+
+// blurFastRadius is the radius above which NewBlur approximates the Gaussian with box blurs
+const blurFastRadius = 12
+
+// NewBlur returns a Blur whose weight falls almost entirely within radius pixels, like BlurGaussian. Large
+// radii are approximated with box blurs.
+func NewBlur(radius int) Blur {
+	return Blur{
+		Sigma: float64(radius) / 3.0,
+		Edge:  EdgeRenormalize,
+		Fast:  radius > blurFastRadius,
+	}
+}
+
+// GaussianKernel returns the weights of a 1D Gaussian of the given standard deviation, out to 3 standard
+// deviations either side of the center, which is at index len/2. The weights sum to 1.
+func GaussianKernel(sigma float64) []float64 {
+	var (
+		radius = int(math.Ceil(3 * sigma))
+		kernel = make([]float64, 2*radius+1)
+		sum    = 0.0
+	)
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	return kernel
+}
+
+// boxesForGauss returns the radii of three box blurs that, applied one after another, closely approximate a
+// Gaussian of the given standard deviation
+func boxesForGauss(sigma float64) [3]int {
+	const n = 3
+	var (
+		ideal = math.Sqrt(12*sigma*sigma/n + 1)
+		lower = int(ideal)
+	)
+	if lower%2 == 0 {
+		lower--
+	}
+	var (
+		upper = lower + 2
+		wl    = float64(lower)
+		m     = int(math.Round((12*sigma*sigma - n*wl*wl - 4*n*wl - 3*n) / (-4*wl - 4)))
+		radii [3]int
+	)
+	for i := range radii {
+		if i < m {
+			radii[i] = (lower - 1) / 2
+		} else {
+			radii[i] = (upper - 1) / 2
+		}
+	}
+	return radii
+}
+
+// Blur blurs the Map in place with the passed Blur
+func (m Map) Blur(b Blur) Map {
+	if b.Sigma <= 0 {
+		return m
+	}
+	if b.Fast {
+		for _, radius := range boxesForGauss(b.Sigma) {
+			m.BlurBox(radius, b.Edge)
+		}
+		return m
+	}
+
+	kernel := GaussianKernel(b.Sigma)
+	filter := func(dst, src []float64) {
+		convolveLine(dst, src, kernel, b.Edge)
+	}
+	return m.separable(filter, filter)
+}
+
+// BlurBox replaces every point with the mean of the square of points within radius of it, in time that doesn't
+// depend on the radius
+func (m Map) BlurBox(radius int, edge EdgeMode) Map {
+	if radius <= 0 {
+		return m
+	}
+	filter := func(dst, src []float64) {
+		boxLine(dst, src, radius, edge)
+	}
+	return m.separable(filter, filter)
+}
+
+// separable filters the Map in place by running alongY over every column, then alongX over every row. Both
+// filters must write the filtered values of src into dst, which is the same length.
+func (m Map) separable(alongY, alongX func(dst, src []float64)) Map {
+	bounds := m.Bounds()
+	if bounds.X == 0 || bounds.Y == 0 {
+		return m
+	}
+
+	ParallelFor(bounds.X, func(x int) {
+		src := make([]float64, bounds.Y)
+		copy(src, m[x])
+		alongY(m[x], src)
+	})
+	ParallelFor(bounds.Y, func(y int) {
+		var (
+			src = make([]float64, bounds.X)
+			dst = make([]float64, bounds.X)
+		)
+		for x := range src {
+			src[x] = m[x][y]
+		}
+		alongX(dst, src)
+		for x := range dst {
+			m[x][y] = dst[x]
+		}
+	})
+	return m
+}
+
+// convolveLine convolves src with a kernel centered on index len(kernel)/2, writing the result to dst. With
+// EdgeRenormalize, the kernel's weights must all be non-negative.
+func convolveLine(dst, src, kernel []float64, edge EdgeMode) {
+	var (
+		n      = len(src)
+		radius = len(kernel) / 2
+		total  = 0.0
+	)
+	for _, w := range kernel {
+		total += w
+	}
+	for i := range dst {
+		var sum, weight float64
+		for k, w := range kernel {
+			j := i + k - radius
+			if j < 0 || j >= n {
+				if j = edgeIndex(j, n, edge); j < 0 {
+					continue
+				}
+			}
+			sum += w * src[j]
+			weight += w
+		}
+		if edge == EdgeRenormalize && weight != 0 {
+			sum *= total / weight
+		}
+		dst[i] = sum
+	}
+}
+
+// boxLine writes the mean of every window of 2*radius+1 points of src to dst, with a running sum
+func boxLine(dst, src []float64, radius int, edge EdgeMode) {
+	var (
+		n  = len(src)
+		at = func(i int) float64 {
+			if i = edgeIndex(i, n, edge); i < 0 {
+				return 0
+			}
+			return src[i]
+		}
+		sum   = 0.0
+		width = float64(2*radius + 1)
+	)
+	for i := -radius; i <= radius; i++ {
+		sum += at(i)
+	}
+	for i := range dst {
+		if edge == EdgeRenormalize {
+			count := MinInt(n-1, i+radius) - MaxInt(0, i-radius) + 1
+			dst[i] = sum / float64(count)
+		} else {
+			dst[i] = sum / width
+		}
+		sum += at(i+radius+1) - at(i-radius)
+	}
+}
@@ -0,0 +1,271 @@
+package zmath
+
+import (
+	"math"
+	"sort"
+)
+
+//                         //
+// - - - CONVOLUTION - - - //
+//                         //
+
+// Convolve replaces every point of the Map with the sum of the points around it, weighted by the kernel. The
+// kernel is a Map of odd width and height, laid over each point centered on its middle, as is (without being
+// flipped, as image filters usually do). With EdgeRenormalize, the weights left past the edge are made up for
+// by scaling the rest up, by how much of the kernel's total weight they hold. That only works for kernels that
+// average, so kernels with negative weights, like Sobel's, are treated as EdgeClamp instead. ConvolveFFT is
+// faster for large kernels.
+func (m Map) Convolve(kernel Map, edge EdgeMode) Map {
+	var (
+		bounds = m.Bounds()
+		kb     = kernel.Bounds()
+		center = VI(kb.X/2, kb.Y/2)
+		total  = 0.0
+		out    = NewMap(bounds, 0)
+	)
+	edge = kernelEdge(edge, kernel...)
+	for _, col := range kernel {
+		for _, w := range col {
+			total += w
+		}
+	}
+
+	ParallelFor(bounds.X, func(x int) {
+		for y := range out[x] {
+			var sum, weight float64
+			for kx, kcol := range kernel {
+				px := edgeIndex(x+kx-center.X, bounds.X, edge)
+				if px < 0 {
+					continue
+				}
+				for ky, w := range kcol {
+					py := edgeIndex(y+ky-center.Y, bounds.Y, edge)
+					if py < 0 {
+						continue
+					}
+					sum += w * m[px][py]
+					weight += w
+				}
+			}
+			if edge == EdgeRenormalize && weight != 0 {
+				sum *= total / weight
+			}
+			out[x][y] = sum
+		}
+	})
+
+	return m.Paste(out, ZVI)
+}
+
+// ConvolveSeparable convolves the Map with the kernel whose weight at (x, y) is kx[x] * ky[y], which is much
+// faster than Convolve for large kernels. Both kernels are centered on their middle, like for Convolve.
+func (m Map) ConvolveSeparable(kx, ky []float64, edge EdgeMode) Map {
+	edge = kernelEdge(edge, kx, ky)
+	return m.separable(func(dst, src []float64) {
+		convolveLine(dst, src, ky, edge)
+	}, func(dst, src []float64) {
+		convolveLine(dst, src, kx, edge)
+	})
+}
+
+// kernelEdge returns how a kernel made up of the passed weights should treat points past the edges, when asked
+// for edge. Renormalizing a kernel with negative weights could scale the points left by anything, such as
+// infinity for kernels that add up to 0, so those are clamped instead.
+func kernelEdge(edge EdgeMode, weights ...[]float64) EdgeMode {
+	if edge != EdgeRenormalize {
+		return edge
+	}
+	for _, ws := range weights {
+		for _, w := range ws {
+			if w < 0 {
+				return EdgeClamp
+			}
+		}
+	}
+	return edge
+}
+
+// kernelFromRows returns a kernel written out the way it looks, one row of constant y at a time
+func kernelFromRows(rows [][]float64) Map {
+	kernel := NewMap(VI(len(rows[0]), len(rows)), 0)
+	for y, row := range rows {
+		for x, w := range row {
+			kernel[x][y] = w
+		}
+	}
+	return kernel
+}
+
+// KernelSobelX returns a new 3x3 Sobel kernel, which finds how steeply the Map rises along +x
+func KernelSobelX() Map {
+	return kernelFromRows([][]float64{
+		{-1, 0, 1},
+		{-2, 0, 2},
+		{-1, 0, 1},
+	})
+}
+
+// KernelSobelY returns a new 3x3 Sobel kernel, which finds how steeply the Map rises along +y
+func KernelSobelY() Map {
+	return kernelFromRows([][]float64{
+		{-1, -2, -1},
+		{0, 0, 0},
+		{1, 2, 1},
+	})
+}
+
+// KernelLaplacian returns a new 3x3 Laplacian kernel, which is 0 wherever the Map is flat
+func KernelLaplacian() Map {
+	return kernelFromRows([][]float64{
+		{0, 1, 0},
+		{1, -4, 1},
+		{0, 1, 0},
+	})
+}
+
+// KernelSharpen returns a new 3x3 sharpening kernel
+func KernelSharpen() Map {
+	return kernelFromRows([][]float64{
+		{0, -1, 0},
+		{-1, 5, -1},
+		{0, -1, 0},
+	})
+}
+
+// KernelEmboss returns a new 3x3 emboss kernel, which makes the Map look lit from the top left
+func KernelEmboss() Map {
+	return kernelFromRows([][]float64{
+		{-2, -1, 0},
+		{-1, 1, 1},
+		{0, 1, 2},
+	})
+}
+
+// Sobel replaces every point of the Map with the magnitude of its Sobel gradient, which is large along edges
+func (m Map) Sobel(edge EdgeMode) Map {
+	var (
+		gx = m.CopyAll().Convolve(KernelSobelX(), edge)
+		gy = m.CopyAll().Convolve(KernelSobelY(), edge)
+	)
+	m.columns(func(x int, col []float64) {
+		for y := range col {
+			col[y] = math.Hypot(gx[x][y], gy[x][y])
+		}
+	})
+	return m
+}
+
+// Laplacian convolves the Map with KernelLaplacian
+func (m Map) Laplacian(edge EdgeMode) Map {
+	return m.Convolve(KernelLaplacian(), edge)
+}
+
+// Sharpen convolves the Map with KernelSharpen
+func (m Map) Sharpen(edge EdgeMode) Map {
+	return m.Convolve(KernelSharpen(), edge)
+}
+
+// Emboss convolves the Map with KernelEmboss
+func (m Map) Emboss(edge EdgeMode) Map {
+	return m.Convolve(KernelEmboss(), edge)
+}
+
+// UnsharpMask sharpens the Map by adding amount times the difference between it and a blurred copy of it
+func (m Map) UnsharpMask(sigma, amount float64, edge EdgeMode) Map {
+	blurred := m.CopyAll().Blur(Blur{
+		Sigma: sigma,
+		Edge:  edge,
+	})
+	m.columns(func(x int, col []float64) {
+		for y := range col {
+			col[y] += amount * (col[y] - blurred[x][y])
+		}
+	})
+	return m
+}
+
+//                          //
+// - - - RANK FILTERS - - - //
+//                          //
+
+// Median replaces every point of the Map with the median of the square of points within radius of it, which
+// smooths out noise without blurring edges
+func (m Map) Median(radius int, edge EdgeMode) Map {
+	var (
+		bounds = m.Bounds()
+		out    = NewMap(bounds, 0)
+	)
+	ParallelFor(bounds.X, func(x int) {
+		window := make([]float64, 0, (2*radius+1)*(2*radius+1))
+		for y := range out[x] {
+			window = window[:0]
+			for dx := -radius; dx <= radius; dx++ {
+				px := edgeIndex(x+dx, bounds.X, edge)
+				if px < 0 {
+					continue
+				}
+				for dy := -radius; dy <= radius; dy++ {
+					if py := edgeIndex(y+dy, bounds.Y, edge); py >= 0 {
+						window = append(window, m[px][py])
+					}
+				}
+			}
+			sort.Float64s(window)
+			if n := len(window); n%2 == 1 {
+				out[x][y] = window[n/2]
+			} else {
+				out[x][y] = (window[n/2-1] + window[n/2]) / 2
+			}
+		}
+	})
+	return m.Paste(out, ZVI)
+}
+
+// Erode replaces every point of the Map with the lowest point within a square of the given radius around it,
+// which shrinks peaks and widens valleys
+func (m Map) Erode(radius int, edge EdgeMode) Map {
+	filter := func(dst, src []float64) {
+		rankLine(dst, src, radius, edge, math.Min)
+	}
+	return m.separable(filter, filter)
+}
+
+// Dilate replaces every point of the Map with the highest point within a square of the given radius around it,
+// which widens peaks and shrinks valleys
+func (m Map) Dilate(radius int, edge EdgeMode) Map {
+	filter := func(dst, src []float64) {
+		rankLine(dst, src, radius, edge, math.Max)
+	}
+	return m.separable(filter, filter)
+}
+
+// Open erodes and then dilates the Map, which removes peaks narrower than the square but leaves the rest as is
+func (m Map) Open(radius int, edge EdgeMode) Map {
+	return m.Erode(radius, edge).Dilate(radius, edge)
+}
+
+// Close dilates and then erodes the Map, which fills in valleys narrower than the square but leaves the rest as
+// is
+func (m Map) Close(radius int, edge EdgeMode) Map {
+	return m.Dilate(radius, edge).Erode(radius, edge)
+}
+
+// TopHat replaces the Map with how far it rises above its Open, which picks out peaks narrower than the square
+func (m Map) TopHat(radius int, edge EdgeMode) Map {
+	return m.SubtractMap(m.CopyAll().Open(radius, edge))
+}
+
+// rankLine writes the pick of every window of 2*radius+1 points of src to dst, where pick chooses between two
+// points, e.g. math.Min
+func rankLine(dst, src []float64, radius int, edge EdgeMode, pick func(a, b float64) float64) {
+	n := len(src)
+	for i := range dst {
+		val := src[i]
+		for j := i - radius; j <= i+radius; j++ {
+			if k := edgeIndex(j, n, edge); k >= 0 {
+				val = pick(val, src[k])
+			}
+		}
+		dst[i] = val
+	}
+}
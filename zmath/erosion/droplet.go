@@ -1,6 +1,10 @@
 package erosion
 
-import "github.com/Isarcus/zarks/zmath"
+import (
+	"math"
+
+	"github.com/Isarcus/zarks/zmath"
+)
 
 type droplet struct {
 	pos zmath.Vec
@@ -10,3 +14,49 @@ type droplet struct {
 	water    float64
 	sediment float64
 }
+
+// run rolls the droplet downhill until it evaporates, stops, or leaves the terrain
+func (d *droplet) run(t *terrain) {
+	cfg := t.cfg
+	for step := 0; step < cfg.Lifetime; step++ {
+		var (
+			oldPos    = d.pos
+			oldHeight = t.heightAt(oldPos)
+			slope     = t.derivativeAt(oldPos)
+		)
+
+		// turn downhill, as much as inertia allows
+		d.dir = d.dir.Scale(cfg.Inertia).Subtract(slope.Scale(1 - cfg.Inertia))
+		length := math.Hypot(d.dir.X, d.dir.Y)
+		if length == 0 {
+			return
+		}
+		d.dir = d.dir.Scale(1 / length)
+		d.pos = d.pos.Add(d.dir)
+		if !t.inBounds(d.pos) {
+			return
+		}
+
+		var (
+			drop     = oldHeight - t.heightAt(d.pos)
+			capacity = math.Max(drop, cfg.MinCapacity) * d.vel * d.water * cfg.Capacity
+		)
+		if drop < 0 || d.sediment > capacity {
+			// droplets going uphill fill in the pit behind them, if they can; otherwise, they drop what they can't carry
+			amount := (d.sediment - capacity) * cfg.Deposition
+			if drop < 0 {
+				amount = math.Min(-drop, d.sediment)
+			}
+			d.sediment -= amount
+			t.deposit(oldPos, amount)
+		} else {
+			// never erode more than the drop, or the droplet would dig itself a pit
+			amount := math.Min((capacity-d.sediment)*cfg.Erosion, drop)
+			d.sediment += amount
+			t.erode(oldPos, amount)
+		}
+
+		d.vel = math.Sqrt(math.Max(0, d.vel*d.vel+drop*cfg.Gravity))
+		d.water *= 1 - cfg.Evaporation
+	}
+}
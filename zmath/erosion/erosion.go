@@ -1,30 +1,113 @@
 package erosion
 
-import (
-	"math/rand"
-	"time"
-)
+import "time"
 
-// Let's call this a work in progress
-
-// Config contains basic erosion parameters
+// Config contains basic erosion parameters. Any parameters left 0 are given their default values.
 type Config struct {
 	Seed     int64
 	Strength float64 // between 0 and 1
 	Test     bool
+
+	// Hydraulic erosion
+	Droplets    int     // how many droplets to simulate. If 0, Strength decides: 1 droplet per pixel at full strength
+	Lifetime    int     // the most steps a droplet can take before it's gone
+	Inertia     float64 // between 0 and 1; how much droplets keep going the same way, rather than straight downhill
+	Capacity    float64 // how much sediment droplets can carry, for their speed, water and how far they drop
+	MinCapacity float64 // the least sediment droplets can carry, so that they still erode flat ground a little
+	Erosion     float64 // between 0 and 1; how much of a droplet's spare capacity it fills each step
+	Deposition  float64 // between 0 and 1; how much of a droplet's excess sediment it drops each step
+	Evaporation float64 // between 0 and 1; how much of a droplet's water evaporates each step
+	Gravity     float64 // how quickly droplets speed up when going downhill
+	Radius      int     // how far around itself a droplet erodes
+
+	// Thermal erosion
+	Talus float64 // the steepest slope, in radians, that loose material can rest at
+	Steps int     // how many times material slides down
+	Creep float64 // between 0 and 1; how much of the material above the talus slope slides down each step
+
+	// Shallow water erosion. MinCapacity, Erosion, Deposition, Evaporation and Gravity apply too.
+	Iterations int     // how many time steps to simulate
+	Rain       float64 // how much water falls on each pixel each time step
+	Solubility float64 // how much sediment water can carry, for how deep and fast it is and how steep the ground is
 }
 
 var defaultConfig = Config{
 	Seed:     0,
 	Strength: 0.5,
+
+	Lifetime:    30,
+	Inertia:     0.05,
+	Capacity:    4,
+	MinCapacity: 0.01,
+	Erosion:     0.3,
+	Deposition:  0.3,
+	Evaporation: 0.01,
+	Gravity:     4,
+	Radius:      3,
+
+	Talus: 0.6,
+	Steps: 50,
+	Creep: 0.5,
+
+	Iterations: 500,
+	Rain:       0.002,
+	Solubility: 0.05,
 }
 
 func (cfg *Config) checkDefaults() {
 	if cfg.Seed == 0 {
-		rand.Seed(time.Now().Unix())
+		cfg.Seed = time.Now().UnixNano()
 	}
 
 	if cfg.Strength == 0 {
 		cfg.Strength = defaultConfig.Strength
 	}
+
+	if cfg.Lifetime == 0 {
+		cfg.Lifetime = defaultConfig.Lifetime
+	}
+	if cfg.Inertia == 0 {
+		cfg.Inertia = defaultConfig.Inertia
+	}
+	if cfg.Capacity == 0 {
+		cfg.Capacity = defaultConfig.Capacity
+	}
+	if cfg.MinCapacity == 0 {
+		cfg.MinCapacity = defaultConfig.MinCapacity
+	}
+	if cfg.Erosion == 0 {
+		cfg.Erosion = defaultConfig.Erosion
+	}
+	if cfg.Deposition == 0 {
+		cfg.Deposition = defaultConfig.Deposition
+	}
+	if cfg.Evaporation == 0 {
+		cfg.Evaporation = defaultConfig.Evaporation
+	}
+	if cfg.Gravity == 0 {
+		cfg.Gravity = defaultConfig.Gravity
+	}
+	if cfg.Radius == 0 {
+		cfg.Radius = defaultConfig.Radius
+	}
+
+	if cfg.Talus == 0 {
+		cfg.Talus = defaultConfig.Talus
+	}
+	if cfg.Steps == 0 {
+		cfg.Steps = defaultConfig.Steps
+	}
+	if cfg.Creep == 0 {
+		cfg.Creep = defaultConfig.Creep
+	}
+
+	if cfg.Iterations == 0 {
+		cfg.Iterations = defaultConfig.Iterations
+	}
+	if cfg.Rain == 0 {
+		cfg.Rain = defaultConfig.Rain
+	}
+	if cfg.Solubility == 0 {
+		cfg.Solubility = defaultConfig.Solubility
+	}
 }
@@ -0,0 +1,151 @@
+package erosion
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// Hydraulic erodes the Map with rain. Droplets are dropped at random over the map and roll downhill, picking up
+// sediment where they speed up and dropping it where they slow down, which carves out gullies and fills in
+// valleys. The same Seed and Config always erode the same Map the same way.
+// Heights are measured relative to the range of the Map, so that the same Config works on Maps of any scale.
+func Hydraulic(m zmath.Map, cfg Config) zmath.Map {
+	cfg.checkDefaults()
+
+	bounds := m.Bounds()
+	if bounds.X < 2 || bounds.Y < 2 {
+		return m
+	}
+	min, max := m.GetMinMax()
+	if max <= min {
+		return m
+	}
+
+	droplets := cfg.Droplets
+	if droplets == 0 {
+		droplets = int(cfg.Strength * float64(bounds.X*bounds.Y))
+	}
+
+	t := &terrain{
+		m:     m,
+		cfg:   cfg,
+		scale: max - min,
+		brush: newBrush(cfg.Radius),
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	for i := 0; i < droplets; i++ {
+		d := droplet{
+			pos:   zmath.V(rng.Float64()*float64(bounds.X-1), rng.Float64()*float64(bounds.Y-1)),
+			vel:   1,
+			water: 1,
+		}
+		d.run(t)
+	}
+
+	return m
+}
+
+// terrain is a Map being eroded by droplets
+type terrain struct {
+	m     zmath.Map
+	cfg   Config
+	scale float64 // the range of the map when erosion began; droplets measure heights in units of it
+	brush []brushPoint
+}
+
+// brushPoint is one of the points around a droplet that it erodes, and how much of the erosion it takes
+type brushPoint struct {
+	off    zmath.VecInt
+	weight float64
+}
+
+// newBrush returns every point within radius of the origin, weighted more the closer they are
+func newBrush(radius int) []brushPoint {
+	brush := make([]brushPoint, 0)
+	for x := -radius; x <= radius; x++ {
+		for y := -radius; y <= radius; y++ {
+			dist := math.Hypot(float64(x), float64(y))
+			if dist < float64(radius) {
+				brush = append(brush, brushPoint{
+					off:    zmath.VI(x, y),
+					weight: float64(radius) - dist,
+				})
+			}
+		}
+	}
+	return brush
+}
+
+// inBounds returns whether pos is far enough inside the map to be interpolated
+func (t *terrain) inBounds(pos zmath.Vec) bool {
+	return pos.X >= 0 && pos.Y >= 0 && pos.X < float64(len(t.m)-1) && pos.Y < float64(len(t.m[0])-1)
+}
+
+// heightAt returns the height of the terrain at pos, interpolated between the four pixels around it
+func (t *terrain) heightAt(pos zmath.Vec) float64 {
+	var (
+		cell   = zmath.VI(int(pos.X), int(pos.Y))
+		fx, fy = pos.X - float64(cell.X), pos.Y - float64(cell.Y)
+		col0   = t.m[cell.X]
+		col1   = t.m[cell.X+1]
+	)
+	return (col0[cell.Y]*(1-fx)*(1-fy) +
+		col1[cell.Y]*fx*(1-fy) +
+		col0[cell.Y+1]*(1-fx)*fy +
+		col1[cell.Y+1]*fx*fy) / t.scale
+}
+
+// derivativeAt returns the derivative of the terrain at pos, interpolated between the four pixels around it
+func (t *terrain) derivativeAt(pos zmath.Vec) zmath.Vec {
+	var (
+		cell   = zmath.VI(int(pos.X), int(pos.Y))
+		fx, fy = pos.X - float64(cell.X), pos.Y - float64(cell.Y)
+		d00    = t.m.DerivativeAt(cell)
+		d10    = t.m.DerivativeAt(cell.AddXY(1, 0))
+		d01    = t.m.DerivativeAt(cell.AddXY(0, 1))
+		d11    = t.m.DerivativeAt(cell.AddXY(1, 1))
+	)
+	return d00.Scale((1 - fx) * (1 - fy)).
+		Add(d10.Scale(fx * (1 - fy))).
+		Add(d01.Scale((1 - fx) * fy)).
+		Add(d11.Scale(fx * fy)).
+		Scale(1 / t.scale)
+}
+
+// deposit adds sediment to the terrain at pos, split between the four pixels around it
+func (t *terrain) deposit(pos zmath.Vec, amount float64) {
+	var (
+		cell   = zmath.VI(int(pos.X), int(pos.Y))
+		fx, fy = pos.X - float64(cell.X), pos.Y - float64(cell.Y)
+		col0   = t.m[cell.X]
+		col1   = t.m[cell.X+1]
+	)
+	amount *= t.scale
+	col0[cell.Y] += amount * (1 - fx) * (1 - fy)
+	col1[cell.Y] += amount * fx * (1 - fy)
+	col0[cell.Y+1] += amount * (1 - fx) * fy
+	col1[cell.Y+1] += amount * fx * fy
+}
+
+// erode removes sediment from the terrain around pos, spread over the brush
+func (t *terrain) erode(pos zmath.Vec, amount float64) {
+	var (
+		cell  = zmath.VI(int(pos.X), int(pos.Y))
+		total = 0.0
+	)
+	for _, pt := range t.brush {
+		if t.m.ContainsCoord(cell.Add(pt.off)) {
+			total += pt.weight
+		}
+	}
+
+	amount *= t.scale
+	for _, pt := range t.brush {
+		if p := cell.Add(pt.off); t.m.ContainsCoord(p) {
+			t.m[p.X][p.Y] -= amount * pt.weight / total
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package erosion
+
+import (
+	"math"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// Flow is the state of the water over a Map after a shallow water simulation
+type Flow struct {
+	Water    zmath.Map    // how deep the water is on each pixel
+	Sediment zmath.Map    // how much sediment the water on each pixel is carrying
+	Velocity zmath.MapVec // which way, and how fast, the water on each pixel is flowing
+}
+
+// Speed returns a NEW Map of how fast the water on each pixel is flowing
+func (f *Flow) Speed() zmath.Map {
+	speed := zmath.NewMap(f.Water.Bounds(), 0)
+	for x := range speed {
+		for y := range speed[x] {
+			speed[x][y] = math.Hypot(f.Velocity[x][y].X, f.Velocity[x][y].Y)
+		}
+	}
+	return speed
+}
+
+// pipeStep is the length of each time step of a shallow water simulation
+const pipeStep = 0.05
+
+// pipeDirs are the four directions water can flow out of a pixel in. Each direction's opposite is at i^1.
+var pipeDirs = [4]zmath.VecInt{
+	{X: -1, Y: 0}, {X: 1, Y: 0},
+	{X: 0, Y: -1}, {X: 0, Y: 1},
+}
+
+// ShallowWater erodes the Map with a grid-based water simulation, and returns where the water ended up. Rain
+// falls evenly over the map and flows between neighboring pixels through virtual pipes, picking up sediment
+// where it flows fast and dropping it where it slows down, for cfg.Iterations time steps. Water can't flow off
+// the edges of the map, so it pools in the lowest places until it evaporates. Every pixel is simulated at once,
+// so this is much faster than Hydraulic on large maps, and it gives rivers and lakes besides.
+// Heights are in the same units as the distance between pixels, like for Thermal.
+func ShallowWater(m zmath.Map, cfg Config) *Flow {
+	cfg.checkDefaults()
+
+	var (
+		bounds = m.Bounds()
+		flow   = &Flow{
+			Water:    zmath.NewMap(bounds, 0),
+			Sediment: zmath.NewMap(bounds, 0),
+			Velocity: zmath.NewMapVec(bounds),
+		}
+		flux     [4]zmath.Map // how much water flows out of each pixel in each direction
+		capacity = zmath.NewMap(bounds, 0)
+		moved    = zmath.NewMap(bounds, 0)
+	)
+	for i := range flux {
+		flux[i] = zmath.NewMap(bounds, 0)
+	}
+
+	for iter := 0; iter < cfg.Iterations; iter++ {
+		flow.Water.Add(cfg.Rain)
+
+		// Water flows out toward lower neighbors faster and faster, but never more than there is
+		zmath.ParallelFor(bounds.X, func(x int) {
+			for y := range m[x] {
+				var (
+					pos   = zmath.VI(x, y)
+					level = m[x][y] + flow.Water[x][y]
+					total = 0.0
+				)
+				for i, dir := range pipeDirs {
+					n := pos.Add(dir)
+					if !m.ContainsCoord(n) {
+						continue
+					}
+					drop := level - m[n.X][n.Y] - flow.Water[n.X][n.Y]
+					f := flux[i][x][y] + pipeStep*cfg.Gravity*drop
+					if f < 0 {
+						f = 0
+					}
+					flux[i][x][y] = f
+					total += f
+				}
+				if total*pipeStep > flow.Water[x][y] {
+					for i := range flux {
+						flux[i][x][y] *= flow.Water[x][y] / (total * pipeStep)
+					}
+				}
+			}
+		})
+
+		// Move the water, and find how fast it's going by how much flowed through each pixel
+		zmath.ParallelFor(bounds.X, func(x int) {
+			for y := range m[x] {
+				var (
+					pos     = zmath.VI(x, y)
+					through [4]float64 // flow through the pixel in each direction, from both sides
+					net     = 0.0
+				)
+				for i, dir := range pipeDirs {
+					out := flux[i][x][y]
+					net -= out
+					through[i] += out
+					if n := pos.Add(dir); m.ContainsCoord(n) {
+						in := flux[i^1][n.X][n.Y]
+						net += in
+						through[i^1] += in
+					}
+				}
+
+				var (
+					depth = flow.Water[x][y] + pipeStep*net
+					mean  = (flow.Water[x][y] + depth) / 2
+				)
+				flow.Water[x][y] = depth
+				if mean > 1e-9 {
+					flow.Velocity[x][y] = zmath.V((through[1]-through[0])/2/mean, (through[3]-through[2])/2/mean)
+				} else {
+					flow.Velocity[x][y] = zmath.ZV
+				}
+			}
+		})
+
+		// Fast water on steep ground can carry more sediment
+		zmath.ParallelFor(bounds.X, func(x int) {
+			for y := range m[x] {
+				var (
+					slope = m.SlopeAt(zmath.VI(x, y))
+					sin   = math.Max(slope/math.Sqrt(1+slope*slope), cfg.MinCapacity)
+					v     = flow.Velocity[x][y]
+				)
+				capacity[x][y] = cfg.Solubility * sin * math.Hypot(v.X, v.Y) * flow.Water[x][y]
+			}
+		})
+
+		// Pick up or drop sediment, then carry it along with the water
+		zmath.ParallelFor(bounds.X, func(x int) {
+			for y := range m[x] {
+				var (
+					c      = capacity[x][y]
+					s      = flow.Sediment[x][y]
+					amount float64
+				)
+				if c > s {
+					amount = pipeStep * cfg.Erosion * (c - s)
+				} else {
+					amount = -pipeStep * cfg.Deposition * (s - c)
+				}
+				m[x][y] -= amount
+				flow.Sediment[x][y] = s + amount
+			}
+		})
+		zmath.ParallelFor(bounds.X, func(x int) {
+			for y := range m[x] {
+				from := zmath.V(float64(x), float64(y)).Subtract(flow.Velocity[x][y].Scale(pipeStep))
+				moved[x][y] = sampleClamped(flow.Sediment, from)
+			}
+		})
+		flow.Sediment, moved = moved, flow.Sediment
+
+		flow.Water.Multiply(1 - cfg.Evaporation)
+	}
+
+	return flow
+}
+
+// sampleClamped returns the value of the map at pos, interpolated between the four pixels around it. Positions
+// outside of the map take the value of the nearest edge.
+func sampleClamped(m zmath.Map, pos zmath.Vec) float64 {
+	var (
+		bounds = m.Bounds()
+		px     = zmath.MinMax(0, float64(bounds.X-1), pos.X)
+		py     = zmath.MinMax(0, float64(bounds.Y-1), pos.Y)
+		x0, y0 = int(px), int(py)
+		x1, y1 = zmath.MinInt(x0+1, bounds.X-1), zmath.MinInt(y0+1, bounds.Y-1)
+		fx, fy = px - float64(x0), py - float64(y0)
+	)
+	return m[x0][y0]*(1-fx)*(1-fy) +
+		m[x1][y0]*fx*(1-fy) +
+		m[x0][y1]*(1-fx)*fy +
+		m[x1][y1]*fx*fy
+}
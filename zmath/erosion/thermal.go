@@ -0,0 +1,91 @@
+package erosion
+
+import (
+	"math"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// thermalNeighbors are the eight pixels around each pixel that material can slide to
+var thermalNeighbors = [8]zmath.VecInt{
+	{X: -1, Y: -1}, {X: 0, Y: -1}, {X: 1, Y: -1},
+	{X: -1, Y: 0}, {X: 1, Y: 0},
+	{X: -1, Y: 1}, {X: 0, Y: 1}, {X: 1, Y: 1},
+}
+
+// Thermal erodes the Map by weathering. Wherever the slope is steeper than the talus angle, loose material
+// slides down to the lower pixels around it, for cfg.Steps steps. This softens cliffs and sharp ridges into
+// scree slopes. Heights are in the same units as the distance between pixels, so Multiply the Map first to
+// choose how tall its terrain is.
+func Thermal(m zmath.Map, cfg Config) zmath.Map {
+	cfg.checkDefaults()
+
+	var (
+		bounds = m.Bounds()
+		talus  = math.Tan(cfg.Talus)
+		moved  = zmath.NewMap(bounds, 0)
+	)
+	for step := 0; step < cfg.Steps; step++ {
+		slopes := m.GetSlopeMap()
+		moved.Clear(0)
+
+		for x := range m {
+			for y, h := range m[x] {
+				pos := zmath.VI(x, y)
+				if !steep(slopes, pos, talus) {
+					continue
+				}
+
+				// find how far each neighbor is below the talus slope, and the most any of them is
+				var (
+					excess [8]float64
+					total  = 0.0
+					most   = 0.0
+				)
+				for i, off := range thermalNeighbors {
+					n := pos.Add(off)
+					if !m.ContainsCoord(n) {
+						continue
+					}
+					dist := math.Hypot(float64(off.X), float64(off.Y))
+					if e := h - m[n.X][n.Y] - talus*dist; e > 0 {
+						excess[i] = e
+						total += e
+						most = math.Max(most, e)
+					}
+				}
+				if total == 0 {
+					continue
+				}
+
+				// slide half of the steepest excess, so that the pixels would meet in the middle, spread out
+				// between the neighbors by how steep each one is
+				amount := cfg.Creep * most / 2
+				moved[x][y] -= amount
+				for i, off := range thermalNeighbors {
+					if excess[i] > 0 {
+						moved[x+off.X][y+off.Y] += amount * excess[i] / total
+					}
+				}
+			}
+		}
+
+		m.AddMap(moved)
+	}
+
+	return m
+}
+
+// steep returns whether the slope at pos, or at any pixel around it, is steeper than talus. The neighbors matter
+// because the slope at the very tip of a spike or the very bottom of a pit is flat.
+func steep(slopes zmath.Map, pos zmath.VecInt, talus float64) bool {
+	if slopes[pos.X][pos.Y] > talus {
+		return true
+	}
+	for _, off := range thermalNeighbors {
+		if n := pos.Add(off); slopes.ContainsCoord(n) && slopes[n.X][n.Y] > talus {
+			return true
+		}
+	}
+	return false
+}
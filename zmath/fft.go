@@ -0,0 +1,350 @@
+package zmath
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// FFT returns the discrete Fourier transform of x, which may be of any length:
+// X[k] = sum over n of x[n] * e^(-2*pi*i*k*n/N)
+func FFT(x []complex128) []complex128 {
+	out := make([]complex128, len(x))
+	copy(out, x)
+	fft(out, false)
+	return out
+}
+
+// IFFT returns the inverse discrete Fourier transform of x, scaled by 1/N so that IFFT(FFT(x)) gives back x
+func IFFT(x []complex128) []complex128 {
+	out := make([]complex128, len(x))
+	copy(out, x)
+	fft(out, true)
+	scale := complex(1/float64(len(x)), 0)
+	for i := range out {
+		out[i] *= scale
+	}
+	return out
+}
+
+// fft transforms x in place, without any scaling. Powers of two are transformed directly, and every other
+// length goes through Bluestein's algorithm, which turns it into a convolution of power of two length.
+func fft(x []complex128, inverse bool) {
+	if n := len(x); n <= 1 {
+		return
+	} else if n&(n-1) == 0 {
+		radix2(x, inverse)
+	} else {
+		bluestein(x, inverse)
+	}
+}
+
+// radix2 transforms x in place, whose length must be a power of two
+func radix2(x []complex128, inverse bool) {
+	var (
+		n     = len(x)
+		shift = bits.LeadingZeros(uint(n)) + 1
+		sign  = -1.0
+	)
+	if inverse {
+		sign = 1
+	}
+	for i := range x {
+		if j := int(bits.Reverse(uint(i)) >> shift); i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	twiddles := make([]complex128, n/2)
+	for k := range twiddles {
+		sin, cos := math.Sincos(sign * 2 * math.Pi * float64(k) / float64(n))
+		twiddles[k] = complex(cos, sin)
+	}
+	for size := 2; size <= n; size *= 2 {
+		var (
+			half   = size / 2
+			stride = n / size
+		)
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				var (
+					a = x[start+k]
+					b = x[start+k+half] * twiddles[k*stride]
+				)
+				x[start+k], x[start+k+half] = a+b, a-b
+			}
+		}
+	}
+}
+
+// bluestein transforms x in place, for any length, by rewriting kn as (k^2 + n^2 - (k-n)^2) / 2
+func bluestein(x []complex128, inverse bool) {
+	var (
+		n     = len(x)
+		m     = 1 << bits.Len(uint(2*n-2))
+		sign  = -1.0
+		chirp = make([]complex128, n)
+		a     = make([]complex128, m)
+		b     = make([]complex128, m)
+	)
+	if inverse {
+		sign = 1
+	}
+	for k := range chirp {
+		// k^2 mod 2n keeps the angle small, and so precise, for large k
+		sq := (k * k) % (2 * n)
+		sin, cos := math.Sincos(sign * math.Pi * float64(sq) / float64(n))
+		chirp[k] = complex(cos, sin)
+	}
+
+	for k := 0; k < n; k++ {
+		a[k] = x[k] * chirp[k]
+		b[k] = cmplx.Conj(chirp[k])
+		if k > 0 {
+			b[m-k] = b[k]
+		}
+	}
+	radix2(a, false)
+	radix2(b, false)
+	for i := range a {
+		a[i] *= b[i]
+	}
+	radix2(a, true)
+
+	scale := complex(1/float64(m), 0)
+	for k := range x {
+		x[k] = a[k] * scale * chirp[k]
+	}
+}
+
+// Spectrum is the 2D Fourier transform of a Map, indexed the same way. Index (0, 0) holds the zero frequency,
+// frequencies rise toward the middle of each axis, and the second half of each axis holds the negative ones.
+type Spectrum [][]complex128
+
+// NewSpectrum returns a new Spectrum of the passed bounds, all 0
+func NewSpectrum(bounds VecInt) Spectrum {
+	s := make(Spectrum, bounds.X)
+	for x := range s {
+		s[x] = make([]complex128, bounds.Y)
+	}
+	return s
+}
+
+// Bounds returns the dimensions of the Spectrum
+func (s Spectrum) Bounds() VecInt {
+	if len(s) == 0 {
+		return ZVI
+	}
+	return VI(len(s), len(s[0]))
+}
+
+// FFT returns the 2D Fourier transform of the Map, of any size. The Map is treated as though it tiles, so
+// anything that doesn't line up across opposite edges shows up as a bright cross through the zero frequency.
+func (m Map) FFT() Spectrum {
+	s := NewSpectrum(m.Bounds())
+	for x := range s {
+		for y := range s[x] {
+			s[x][y] = complex(m[x][y], 0)
+		}
+	}
+	return s.transform(false)
+}
+
+// Inverse returns a NEW Map from the inverse 2D Fourier transform of the Spectrum, keeping only the real part
+func (s Spectrum) Inverse() Map {
+	var (
+		bounds = s.Bounds()
+		m      = NewMap(bounds, 0)
+		scale  = 1 / float64(bounds.X*bounds.Y)
+	)
+	s = s.Copy().transform(true)
+	m.columns(func(x int, col []float64) {
+		for y := range col {
+			col[y] = real(s[x][y]) * scale
+		}
+	})
+	return m
+}
+
+// transform runs the FFT in place along every column, then along every row
+func (s Spectrum) transform(inverse bool) Spectrum {
+	bounds := s.Bounds()
+	if bounds.X == 0 || bounds.Y == 0 {
+		return s
+	}
+	ParallelFor(bounds.X, func(x int) {
+		fft(s[x], inverse)
+	})
+	ParallelFor(bounds.Y, func(y int) {
+		row := make([]complex128, bounds.X)
+		for x := range row {
+			row[x] = s[x][y]
+		}
+		fft(row, inverse)
+		for x := range row {
+			s[x][y] = row[x]
+		}
+	})
+	return s
+}
+
+// Copy returns a deepcopy of the Spectrum
+func (s Spectrum) Copy() Spectrum {
+	c := NewSpectrum(s.Bounds())
+	for x := range c {
+		copy(c[x], s[x])
+	}
+	return c
+}
+
+// FrequencyAt returns the frequency at pos, in cycles per pixel along each axis, within [-0.5, 0.5]
+func (s Spectrum) FrequencyAt(pos VecInt) Vec {
+	bounds := s.Bounds()
+	return V(frequency(pos.X, bounds.X), frequency(pos.Y, bounds.Y))
+}
+
+// frequency returns the frequency of index k of an FFT of length n, in cycles per sample
+func frequency(k, n int) float64 {
+	if k > n/2 {
+		k -= n
+	}
+	return float64(k) / float64(n)
+}
+
+// Magnitude returns a NEW Map of how strong each frequency of the Spectrum is. It's usually much easier to see
+// anything in after a Map.FFTShift and a CustomMod(math.Log1p).
+func (s Spectrum) Magnitude() Map {
+	return s.each(cmplx.Abs)
+}
+
+// Phase returns a NEW Map of the phase of each frequency of the Spectrum, in radians within [-pi, pi]
+func (s Spectrum) Phase() Map {
+	return s.each(cmplx.Phase)
+}
+
+// each returns a NEW Map of eachFunc of every point of the Spectrum
+func (s Spectrum) each(eachFunc func(complex128) float64) Map {
+	m := NewMap(s.Bounds(), 0)
+	m.columns(func(x int, col []float64) {
+		for y := range col {
+			col[y] = eachFunc(s[x][y])
+		}
+	})
+	return m
+}
+
+// Filter multiplies every point of the Spectrum by response of its frequency, in cycles per pixel, regardless
+// of direction
+func (s Spectrum) Filter(response func(freq float64) float64) Spectrum {
+	for x := range s {
+		for y := range s[x] {
+			f := s.FrequencyAt(VI(x, y))
+			s[x][y] *= complex(response(math.Hypot(f.X, f.Y)), 0)
+		}
+	}
+	return s
+}
+
+// FFTShift returns a NEW Map with its quadrants swapped, so that the zero frequency of a Spectrum's Magnitude
+// or Phase lands in the middle
+func (m Map) FFTShift() Map {
+	var (
+		bounds  = m.Bounds()
+		shifted = NewMap(bounds, 0)
+	)
+	for x := range m {
+		for y := range m[x] {
+			shifted[(x+bounds.X/2)%bounds.X][(y+bounds.Y/2)%bounds.Y] = m[x][y]
+		}
+	}
+	return shifted
+}
+
+//                               //
+// - - - FREQUENCY FILTERS - - - //
+//                               //
+
+// FilterFrequencies multiplies every frequency of the Map by response of it, in cycles per pixel, which is
+// within [0, 0.5] along each axis. The Map is treated as though it tiles, so its edges affect each other.
+func (m Map) FilterFrequencies(response func(freq float64) float64) Map {
+	return m.Paste(m.FFT().Filter(response).Inverse(), ZVI)
+}
+
+// LowPass removes every frequency of the Map above cutoff, in cycles per pixel, smoothing it out. A hard cutoff
+// can leave ripples next to sharp edges; FilterFrequencies takes any response for a gentler one.
+func (m Map) LowPass(cutoff float64) Map {
+	return m.BandPass(0, cutoff)
+}
+
+// HighPass removes every frequency of the Map up to and including cutoff, in cycles per pixel, leaving only the
+// fine detail. A LowPass and a HighPass with the same cutoff add back up to the Map.
+func (m Map) HighPass(cutoff float64) Map {
+	return m.FilterFrequencies(func(freq float64) float64 {
+		if freq <= cutoff {
+			return 0
+		}
+		return 1
+	})
+}
+
+// BandPass removes every frequency of the Map outside [low, high], in cycles per pixel
+func (m Map) BandPass(low, high float64) Map {
+	return m.FilterFrequencies(func(freq float64) float64 {
+		if freq < low || freq > high {
+			return 0
+		}
+		return 1
+	})
+}
+
+// ConvolveFFT gives the same result as Convolve, but multiplies in the frequency domain instead, so it takes
+// about as long for any size of kernel. It's worth it once the kernel is more than about 10x10.
+func (m Map) ConvolveFFT(kernel Map, edge EdgeMode) Map {
+	var (
+		bounds = m.Bounds()
+		kb     = kernel.Bounds()
+		before = VI(kb.X/2, kb.Y/2)
+		after  = kb.Subtract(before).Subtract(VI(1, 1))
+	)
+	if bounds.X == 0 || bounds.Y == 0 || kb.X == 0 || kb.Y == 0 {
+		return m
+	}
+	edge = kernelEdge(edge, kernel...)
+
+	// With enough padding that the kernel never wraps around, every point of the Map only picks up what the
+	// kernel overlaps, just like with Convolve
+	var (
+		padded = m.Pad(before, after, edge)
+		size   = padded.Bounds()
+		kpad   = NewMap(size, 0).Paste(kernel, ZVI)
+		out    = correlate(padded.FFT(), kpad.FFT())
+	)
+	if edge == EdgeRenormalize {
+		var (
+			mask    = NewMap(size, 0).Paste(NewMap(bounds, 1), before)
+			weights = correlate(mask.FFT(), kpad.FFT())
+			total   = kernel.GetSum()
+		)
+		for x := 0; x < bounds.X; x++ {
+			for y := 0; y < bounds.Y; y++ {
+				// anything this small is only rounding error, from points the kernel doesn't actually weight
+				if weights[x][y] > total*1e-9 {
+					out[x][y] *= total / weights[x][y]
+				}
+			}
+		}
+	}
+
+	return m.Paste(out, ZVI)
+}
+
+// correlate returns a NEW Map of the sum of a's Map times b's Map, shifted by every possible offset, wrapping
+// around the edges, from their Spectrums
+func correlate(a, b Spectrum) Map {
+	for x := range a {
+		for y := range a[x] {
+			a[x][y] *= cmplx.Conj(b[x][y])
+		}
+	}
+	return a.Inverse()
+}
@@ -0,0 +1,483 @@
+package zmath
+
+import (
+	"math"
+)
+
+// FlatMap is a set of 2D raster data, like Map, but stored in a single contiguous slice. Data is laid out column
+// by column just like a Map, so the cell at (x, y) is Data[x*Stride+y]. Stride is usually equal to Height, except
+// for views into a larger FlatMap, whose columns are spread out across the larger FlatMap's data.
+type FlatMap struct {
+	Width, Height int
+	Stride        int
+	Data          []float64
+}
+
+// NewFlatMap returns a FlatMap of the given bounds, with all cells set to the given initial value
+func NewFlatMap(bounds VecInt, initValue float64) FlatMap {
+	bounds = VI(MaxInt(bounds.X, 0), MaxInt(bounds.Y, 0))
+	f := FlatMap{
+		Width:  bounds.X,
+		Height: bounds.Y,
+		Stride: bounds.Y,
+		Data:   make([]float64, bounds.X*bounds.Y),
+	}
+	if initValue != 0 {
+		for i := range f.Data {
+			f.Data[i] = initValue
+		}
+	}
+	return f
+}
+
+// ToFlat returns a FlatMap copy of the called Map
+func (m Map) ToFlat() FlatMap {
+	f := NewFlatMap(m.Bounds(), 0)
+	for x, row := range m {
+		copy(f.Column(x), row)
+	}
+	return f
+}
+
+// ToMap returns a Map that shares the called FlatMap's data, so that changes to either are reflected in the
+// other. No data is copied, which makes this a cheap way to pass a FlatMap to anything that expects a Map.
+func (f FlatMap) ToMap() Map {
+	m := make(Map, f.Width)
+	for x := range m {
+		m[x] = f.Column(x)
+	}
+	return m
+}
+
+// Column returns column x of the FlatMap, which shares the FlatMap's data. It does NOT bounds-check!
+func (f FlatMap) Column(x int) []float64 {
+	start := x * f.Stride
+	return f.Data[start : start+f.Height : start+f.Height]
+}
+
+// View returns a FlatMap that shares the data of the portion of the called FlatMap between min and max.
+// Unlike Copy, the view is clipped to the called FlatMap's bounds.
+func (f FlatMap) View(min, max VecInt) FlatMap {
+	r := RI(min, max)
+	r.Min = VI(MinInt(MaxInt(r.Min.X, 0), f.Width), MinInt(MaxInt(r.Min.Y, 0), f.Height))
+	r.Max = VI(MinInt(MaxInt(r.Max.X, 0), f.Width), MinInt(MaxInt(r.Max.Y, 0), f.Height))
+
+	view := FlatMap{
+		Width:  r.Dx(),
+		Height: r.Dy(),
+		Stride: f.Stride,
+	}
+	if view.Width > 0 && view.Height > 0 {
+		start := r.Min.X*f.Stride + r.Min.Y
+		view.Data = f.Data[start : start+(view.Width-1)*f.Stride+view.Height]
+	}
+	return view
+}
+
+// IsContiguous returns whether the FlatMap's columns directly follow one another in memory, which is true of
+// every FlatMap except for some views.
+func (f FlatMap) IsContiguous() bool {
+	return f.Stride == f.Height || f.Width <= 1
+}
+
+// At returns the value of the FlatMap at the coordinates specified by the passed VecInt. It does NOT bounds-check!
+func (f FlatMap) At(pos VecInt) float64 {
+	return f.Data[pos.X*f.Stride+pos.Y]
+}
+
+// Set sets the point in the FlatMap at the desired coordinates to the passed value. It does NOT bounds-check!
+func (f FlatMap) Set(pos VecInt, value float64) {
+	f.Data[pos.X*f.Stride+pos.Y] = value
+}
+
+// PtrTo returns a pointer to the FlatMap index at the desired coordinates
+func (f FlatMap) PtrTo(pos VecInt) *float64 {
+	return &f.Data[pos.X*f.Stride+pos.Y]
+}
+
+// Bounds returns the bounds of a FlatMap
+func (f FlatMap) Bounds() VecInt {
+	return VI(f.Width, f.Height)
+}
+
+// Area returns the area of the FlatMap as determined by its width and height.
+func (f FlatMap) Area() float64 {
+	return float64(f.Width * f.Height)
+}
+
+// ContainsCoord tells you whether the specified coordinate is inside the called FlatMap
+func (f FlatMap) ContainsCoord(pos VecInt) bool {
+	return pos.X >= 0 && pos.Y >= 0 && pos.X < f.Width && pos.Y < f.Height
+}
+
+// each calls fn on every column of the FlatMap, in parallel if the FlatMap is big enough
+func (f FlatMap) each(fn func(col []float64)) FlatMap {
+	f.columns(func(_ int, col []float64) {
+		fn(col)
+	})
+	return f
+}
+
+// eachPair calls fn on every column of the FlatMap, along with the same column of another FlatMap
+func (f FlatMap) eachPair(g FlatMap, fn func(col, other []float64)) FlatMap {
+	if f.Bounds() != g.Bounds() {
+		return f
+	}
+	f.columns(func(x int, col []float64) {
+		fn(col, g.Column(x))
+	})
+	return f
+}
+
+//                     //
+// - - - STATS - - -   //
+//                     //
+
+// Clear sets all points on the FlatMap equal to the passed value
+func (f FlatMap) Clear(value float64) FlatMap {
+	return f.each(func(col []float64) {
+		for i := range col {
+			col[i] = value
+		}
+	})
+}
+
+// Zero wipes the called FlatMap entirely, setting all values to 0
+func (f FlatMap) Zero() FlatMap {
+	return f.Clear(0)
+}
+
+// GetSum returns the sum of all FlatMap elements
+func (f FlatMap) GetSum() float64 {
+	return sumColumns(f.Width, f.columns)
+}
+
+// GetMean returns the mean of all FlatMap elements
+func (f FlatMap) GetMean() float64 {
+	return f.GetSum() / f.Area()
+}
+
+// GetMin returns the minimum of all FlatMap elements
+func (f FlatMap) GetMin() float64 {
+	min, _ := f.GetMinMax()
+	return min
+}
+
+// GetMax returns the maximum of all FlatMap elements
+func (f FlatMap) GetMax() float64 {
+	_, max := f.GetMinMax()
+	return max
+}
+
+// GetMinMax returns the min and max of the called FlatMap.
+func (f FlatMap) GetMinMax() (min, max float64) {
+	return minMaxColumns(f.Width, f.columns)
+}
+
+// GetRange returns the range of the called FlatMap.
+func (f FlatMap) GetRange() float64 {
+	min, max := f.GetMinMax()
+	return max - min
+}
+
+// ToLinear copies a FlatMap's data to a Set
+func (f FlatMap) ToLinear() Set {
+	linear := make(Set, 0, f.Width*f.Height)
+	for x := 0; x < f.Width; x++ {
+		linear = append(linear, f.Column(x)...)
+	}
+	return linear
+}
+
+//                        //
+// - - - ARITHMETIC - - - //
+//                        //
+
+// Add the passed value to every datapoint
+func (f FlatMap) Add(addend float64) FlatMap {
+	return f.each(func(col []float64) {
+		for i := range col {
+			col[i] += addend
+		}
+	})
+}
+
+// Subtract the passed value from every datapoint
+func (f FlatMap) Subtract(subtrahend float64) FlatMap {
+	return f.Add(-subtrahend)
+}
+
+// Multiply every data point by the passed value
+func (f FlatMap) Multiply(multiplicand float64) FlatMap {
+	return f.each(func(col []float64) {
+		for i := range col {
+			col[i] *= multiplicand
+		}
+	})
+}
+
+// AddMap adds the passed FlatMap to the called FlatMap. Nothing happens if their bounds don't match.
+func (f FlatMap) AddMap(addend FlatMap) FlatMap {
+	return f.eachPair(addend, func(col, other []float64) {
+		for i := range col {
+			col[i] += other[i]
+		}
+	})
+}
+
+// SubtractMap subtracts the passed FlatMap from the called FlatMap. Nothing happens if their bounds don't match.
+func (f FlatMap) SubtractMap(subtrahend FlatMap) FlatMap {
+	return f.eachPair(subtrahend, func(col, other []float64) {
+		for i := range col {
+			col[i] -= other[i]
+		}
+	})
+}
+
+// GeometricMean calculates the geometric mean of two FlatMaps
+func (f FlatMap) GeometricMean(by FlatMap) FlatMap {
+	return f.eachPair(by, func(col, other []float64) {
+		for i := range col {
+			col[i] = math.Sqrt(math.Abs(col[i] * other[i]))
+		}
+	})
+}
+
+// Interpolate interpolates a FlatMap between two values, just like Map.Interpolate
+func (f FlatMap) Interpolate(newMin, newMax float64) FlatMap {
+	min, max := f.GetMinMax()
+	if max == min {
+		return f.Clear(newMin)
+	}
+	oldRange, newRange := max-min, newMax-newMin
+	return f.each(func(col []float64) {
+		for i := range col {
+			col[i] = ((col[i]-min)/oldRange)*newRange + newMin
+		}
+	})
+}
+
+// MakeUniform makes the called FlatMap follow a uniform distribution.
+// This will not change the original min and max values of the called FlatMap.
+func (f FlatMap) MakeUniform() FlatMap {
+	f.ToMap().MakeUniform()
+	return f
+}
+
+// SetMin sets every value in the FlatMap that is less than the passed value TO the passed value.
+func (f FlatMap) SetMin(value float64) FlatMap {
+	return f.each(func(col []float64) {
+		for i := range col {
+			if col[i] < value {
+				col[i] = value
+			}
+		}
+	})
+}
+
+// SetMax sets every value in the FlatMap that is greater than the passed value TO the passed value.
+func (f FlatMap) SetMax(value float64) FlatMap {
+	return f.each(func(col []float64) {
+		for i := range col {
+			if col[i] > value {
+				col[i] = value
+			}
+		}
+	})
+}
+
+// Replace replaces all occurrences of a value with another value
+func (f FlatMap) Replace(value, with float64) FlatMap {
+	return f.each(func(col []float64) {
+		for i := range col {
+			if col[i] == value {
+				col[i] = with
+			}
+		}
+	})
+}
+
+// ReplaceNot replaces all data that is NOT equal to the passed value with another value
+func (f FlatMap) ReplaceNot(value, with float64) FlatMap {
+	return f.each(func(col []float64) {
+		for i := range col {
+			if col[i] != value {
+				col[i] = with
+			}
+		}
+	})
+}
+
+// CustomMod uses the passed function to modify every value of the called FlatMap
+// As with Map.CustomMod, modFunc must be safe to call from several goroutines at once.
+func (f FlatMap) CustomMod(modFunc func(float64) float64) FlatMap {
+	return f.each(func(col []float64) {
+		for i := range col {
+			col[i] = modFunc(col[i])
+		}
+	})
+}
+
+// CustomModAt uses the passed function to modify the values ONLY at the specified points
+func (f FlatMap) CustomModAt(points []VecInt, modFunc func(float64) float64) FlatMap {
+	for _, p := range points {
+		if f.ContainsCoord(p) {
+			ptr := f.PtrTo(p)
+			*ptr = modFunc(*ptr)
+		}
+	}
+	return f
+}
+
+//                          //
+// - - - TRANSFORMING - - - //
+//                          //
+
+// FlipVertical flips the FlatMap across its X-axis.
+func (f FlatMap) FlipVertical() FlatMap {
+	return f.each(func(col []float64) {
+		for lo, hi := 0, len(col)-1; lo < hi; lo, hi = lo+1, hi-1 {
+			col[lo], col[hi] = col[hi], col[lo]
+		}
+	})
+}
+
+// FlipHorizontal flips the FlatMap across its Y-axis.
+func (f FlatMap) FlipHorizontal() FlatMap {
+	ParallelForN(f.Width/2, workersFor(f.Width*f.Height), func(x int) {
+		a, b := f.Column(x), f.Column(f.Width-1-x)
+		for y := range a {
+			a[y], b[y] = b[y], a[y]
+		}
+	})
+	return f
+}
+
+// Copy returns a deepcopy of the portion of the FlatMap specified by the min and max coordinates.
+// As with Map.Copy, any out-of-bounds coordinates will be set to zero.
+func (f FlatMap) Copy(min, max VecInt) FlatMap {
+	copyMap := NewFlatMap(max.Subtract(min), 0)
+	copyMap.Paste(f.View(min, max), VI(MaxInt(-min.X, 0), MaxInt(-min.Y, 0)))
+	return copyMap
+}
+
+// CopyAll deepcopies an entire FlatMap and returns the copy. The copy is always contiguous.
+func (f FlatMap) CopyAll() FlatMap {
+	copyMap := NewFlatMap(f.Bounds(), 0)
+	copyMap.columns(func(x int, col []float64) {
+		copy(col, f.Column(x))
+	})
+	return copyMap
+}
+
+// Paste pastes all of the data from pasteMe into a FlatMap, starting at the specified coordinate in the called
+// FlatMap. Anything that would land outside the called FlatMap is ignored.
+func (f FlatMap) Paste(pasteMe FlatMap, at VecInt) FlatMap {
+	dst := f.View(at, at.Add(pasteMe.Bounds()))
+	src := pasteMe.View(VI(-at.X, -at.Y), f.Bounds().Subtract(at))
+	dst.columns(func(x int, col []float64) {
+		copy(col, src.Column(x))
+	})
+	return f
+}
+
+// BlurGaussian blurs gaussly, just like Map.BlurGaussian
+func (f FlatMap) BlurGaussian(radius int) FlatMap {
+	f.ToMap().BlurGaussian(radius)
+	return f
+}
+
+// Blur blurs the FlatMap in place with the passed Blur
+func (f FlatMap) Blur(b Blur) FlatMap {
+	f.ToMap().Blur(b)
+	return f
+}
+
+// BlurBox blurs the FlatMap just like Map.BlurBox
+func (f FlatMap) BlurBox(radius int, edge EdgeMode) FlatMap {
+	f.ToMap().BlurBox(radius, edge)
+	return f
+}
+
+// Sample returns the value of the FlatMap at any position, just like Map.Sample
+func (f FlatMap) Sample(pos Vec) float64 {
+	return f.ToMap().Sample(pos)
+}
+
+// Resize returns a NEW FlatMap of the passed bounds, resampled just like Map.Resize
+func (f FlatMap) Resize(bounds VecInt, filter Filter) FlatMap {
+	return f.ToMap().Resize(bounds, filter).ToFlat()
+}
+
+//                   //
+// - - - SLOPE - - - //
+//                   //
+
+// DerivativeAt returns the derivative at the desired point on the FlatMap as a Vec{X: dh/dx, Y: dh/dy}, using the
+// same weighting as Map.DerivativeAt. Returns {0, 0} if out of bounds.
+func (f FlatMap) DerivativeAt(pos VecInt) Vec {
+	if !f.ContainsCoord(pos) {
+		return Vec{}
+	}
+
+	var (
+		dh     = Vec{}
+		weight = Vec{}
+		val    = f.At(pos)
+	)
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			near := pos.AddXY(dx, dy)
+			if (dx == 0 && dy == 0) || !f.ContainsCoord(near) {
+				continue
+			}
+
+			// Direct neighbors count twice as much as diagonal ones
+			w := 1.0
+			if dx == 0 || dy == 0 {
+				w = 2.0
+			}
+			diff := w * (f.At(near) - val)
+			if dx != 0 {
+				dh.X += float64(dx) * diff
+				weight.X += w
+			}
+			if dy != 0 {
+				dh.Y += float64(dy) * diff
+				weight.Y += w
+			}
+		}
+	}
+
+	dh.X /= weight.X
+	dh.Y /= weight.Y
+
+	return dh
+}
+
+// GradientAt returns the angle of the gradient at the desired coordinate using Atan2
+func (f FlatMap) GradientAt(pos VecInt) float64 {
+	d := f.DerivativeAt(pos)
+	return math.Atan2(d.Y, d.X)
+}
+
+// SlopeAt returns the slope at the point
+func (f FlatMap) SlopeAt(pos VecInt) float64 {
+	return DistanceFormula(f.DerivativeAt(pos), ZV)
+}
+
+// GetSlopeMap returns a NEW FlatMap of slopes.
+func (f FlatMap) GetSlopeMap() FlatMap {
+	slopeMap := NewFlatMap(f.Bounds(), 0)
+	slopeMap.columns(func(x int, col []float64) {
+		for y := range col {
+			col[y] = f.SlopeAt(VI(x, y))
+		}
+	})
+	return slopeMap
+}
+
+// Save saves a FlatMap in the .zmap format, exactly as Map.Save would
+func (f FlatMap) Save(path string) error {
+	return f.ToMap().Save(path)
+}
@@ -0,0 +1,98 @@
+package hydrology
+
+import (
+	"container/heap"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// Neighbors are the eight cells around each cell that water can flow to, counterclockwise from +x. A D8 flow
+// direction is an index into Neighbors.
+var Neighbors = [8]zmath.VecInt{
+	{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}, {X: -1, Y: 1},
+	{X: -1, Y: 0}, {X: -1, Y: -1}, {X: 0, Y: -1}, {X: 1, Y: -1},
+}
+
+// FillDepressions returns a NEW Map with every depression filled up to the height at which it would spill over,
+// so that water anywhere can drain off an edge of the map. Each filled cell is made at least epsilon higher than
+// the cell it drains into, so that filled areas still slope toward their outlet; with an epsilon of 0, they are
+// left perfectly flat, and have no flow direction.
+func FillDepressions(m zmath.Map, epsilon float64) zmath.Map {
+	var (
+		filled = m.CopyAll()
+		bounds = m.Bounds()
+		done   = make([][]bool, bounds.X)
+		open   = &cellHeap{}
+	)
+	for x := range done {
+		done[x] = make([]bool, bounds.Y)
+	}
+
+	// Flood inward from the edges of the map, always from the lowest cell reached so far
+	for x := range filled {
+		for y := range filled[x] {
+			if x == 0 || y == 0 || x == bounds.X-1 || y == bounds.Y-1 {
+				done[x][y] = true
+				heap.Push(open, cell{zmath.VI(x, y), filled[x][y]})
+			}
+		}
+	}
+	for open.Len() > 0 {
+		c := heap.Pop(open).(cell)
+		for _, off := range Neighbors {
+			n := c.pos.Add(off)
+			if !filled.ContainsCoord(n) || done[n.X][n.Y] {
+				continue
+			}
+			done[n.X][n.Y] = true
+			if filled[n.X][n.Y] < c.height+epsilon {
+				filled[n.X][n.Y] = c.height + epsilon
+			}
+			heap.Push(open, cell{n, filled[n.X][n.Y]})
+		}
+	}
+
+	return filled
+}
+
+// cell is a position on a Map, along with its height
+type cell struct {
+	pos    zmath.VecInt
+	height float64
+}
+
+// cellHeap is a min-heap of cells, by height. Cells of the same height come out in the order they went in, so
+// that filling is deterministic.
+type cellHeap struct {
+	cells []cell
+	order []int
+	count int
+}
+
+func (h *cellHeap) Len() int { return len(h.cells) }
+
+func (h *cellHeap) Less(i, j int) bool {
+	if h.cells[i].height != h.cells[j].height {
+		return h.cells[i].height < h.cells[j].height
+	}
+	return h.order[i] < h.order[j]
+}
+
+func (h *cellHeap) Swap(i, j int) {
+	h.cells[i], h.cells[j] = h.cells[j], h.cells[i]
+	h.order[i], h.order[j] = h.order[j], h.order[i]
+}
+
+func (h *cellHeap) Push(x interface{}) {
+	h.cells = append(h.cells, x.(cell))
+	h.order = append(h.order, h.count)
+	h.count++
+}
+
+func (h *cellHeap) Pop() interface{} {
+	last := len(h.cells) - 1
+	c := h.cells[last]
+	h.cells = h.cells[:last]
+	h.order = h.order[:last]
+	return c
+}
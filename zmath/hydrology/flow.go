@@ -0,0 +1,210 @@
+package hydrology
+
+import (
+	"math"
+	"sort"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// NoFlow is the D8 direction of cells that water can't flow out of, such as pits and flat areas
+const NoFlow int8 = -1
+
+// Directions holds the D8 flow direction of every cell of a Map, as an index into Neighbors, or NoFlow
+type Directions [][]int8
+
+// D8 returns the direction water flows out of each cell of the Map: toward whichever of its eight neighbors is
+// steepest downhill. Run the Map through FillDepressions first, or water will get stuck in every pit.
+func D8(m zmath.Map) Directions {
+	bounds := m.Bounds()
+	dirs := make(Directions, bounds.X)
+	zmath.ParallelFor(bounds.X, func(x int) {
+		dirs[x] = make([]int8, bounds.Y)
+		for y := range dirs[x] {
+			var (
+				pos      = zmath.VI(x, y)
+				dir      = NoFlow
+				steepest = 0.0
+			)
+			for i, off := range Neighbors {
+				n := pos.Add(off)
+				if !m.ContainsCoord(n) {
+					continue
+				}
+				if slope := (m[x][y] - m[n.X][n.Y]) / neighborDist(i); slope > steepest {
+					dir, steepest = int8(i), slope
+				}
+			}
+			dirs[x][y] = dir
+		}
+	})
+	return dirs
+}
+
+// neighborDist returns the distance to Neighbors[i]
+func neighborDist(i int) float64 {
+	if i%2 == 1 {
+		return math.Sqrt2
+	}
+	return 1
+}
+
+// Bounds returns the dimensions of the Directions
+func (d Directions) Bounds() zmath.VecInt {
+	if len(d) == 0 {
+		return zmath.ZVI
+	}
+	return zmath.VI(len(d), len(d[0]))
+}
+
+// Downstream returns the cell that water at pos flows into, and false if it doesn't flow anywhere on the map
+func (d Directions) Downstream(pos zmath.VecInt) (zmath.VecInt, bool) {
+	dir := d[pos.X][pos.Y]
+	if dir == NoFlow {
+		return pos, false
+	}
+	n := pos.Add(Neighbors[dir])
+	if n.X < 0 || n.Y < 0 || n.X >= len(d) || n.Y >= len(d[0]) {
+		return pos, false
+	}
+	return n, true
+}
+
+// Accumulate returns a NEW Map of how many cells drain through each cell, counting itself
+func (d Directions) Accumulate() zmath.Map {
+	var (
+		bounds   = d.Bounds()
+		acc      = zmath.NewMap(bounds, 1)
+		upstream = zmath.NewMap(bounds, 0) // how many cells drain straight into each cell
+		queue    = make([]zmath.VecInt, 0)
+	)
+	for x := range d {
+		for y := range d[x] {
+			if n, ok := d.Downstream(zmath.VI(x, y)); ok {
+				upstream[n.X][n.Y]++
+			}
+		}
+	}
+
+	// Start from the cells that nothing drains into, and only pass a cell's water on once everything upstream
+	// of it has been passed on
+	for x := range d {
+		for y := range d[x] {
+			if upstream[x][y] == 0 {
+				queue = append(queue, zmath.VI(x, y))
+			}
+		}
+	}
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		if n, ok := d.Downstream(pos); ok {
+			acc[n.X][n.Y] += acc[pos.X][pos.Y]
+			if upstream[n.X][n.Y]--; upstream[n.X][n.Y] == 0 {
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	return acc
+}
+
+// DInfinity returns the angle, in radians within [0, 2pi), that water flows out of each cell of the Map. Unlike
+// D8, water may flow in any direction, down the steepest of the eight triangular facets around each cell.
+// Cells that water can't flow out of are given an angle of -1.
+func DInfinity(m zmath.Map) zmath.Map {
+	angles := zmath.NewMap(m.Bounds(), -1)
+	zmath.ParallelFor(len(m), func(x int) {
+		for y := range m[x] {
+			var (
+				pos      = zmath.VI(x, y)
+				steepest = 0.0
+			)
+			// each facet spans from a side neighbor to the corner neighbor next to it
+			for i := 0; i < 8; i++ {
+				side, corner := i&^1, (i&^1+1+(i&1)*6)%8
+				var (
+					e1, ok1 = heightAt(m, pos.Add(Neighbors[side]))
+					e2, ok2 = heightAt(m, pos.Add(Neighbors[corner]))
+				)
+				if !ok1 || !ok2 {
+					continue
+				}
+
+				var (
+					s1    = m[x][y] - e1 // slope toward the side neighbor
+					s2    = e1 - e2      // slope from there toward the corner
+					r     = math.Atan2(s2, s1)
+					slope = math.Hypot(s1, s2)
+				)
+				if r < 0 {
+					r, slope = 0, s1
+				} else if r > math.Pi/4 {
+					r, slope = math.Pi/4, (m[x][y]-e2)/math.Sqrt2
+				}
+				if slope <= steepest {
+					continue
+				}
+
+				// rotate from the side neighbor toward the corner
+				angle := float64(side)*math.Pi/4 + r
+				if corner != side+1 {
+					angle = float64(side)*math.Pi/4 - r
+				}
+				steepest = slope
+				angles[x][y] = math.Mod(angle+2*math.Pi, 2*math.Pi)
+			}
+		}
+	})
+	return angles
+}
+
+// heightAt returns the height of the Map at pos, and false if pos is off of the Map
+func heightAt(m zmath.Map, pos zmath.VecInt) (float64, bool) {
+	if !m.ContainsCoord(pos) {
+		return 0, false
+	}
+	return m[pos.X][pos.Y], true
+}
+
+// AccumulateDInfinity returns a NEW Map of how many cells drain through each cell, counting itself, when water
+// flows at the passed D-infinity angles over the Map. Water is split between the two neighbors on either side
+// of each angle, by how close the angle is to each one.
+func AccumulateDInfinity(m zmath.Map, angles zmath.Map) zmath.Map {
+	var (
+		bounds = m.Bounds()
+		acc    = zmath.NewMap(bounds, 1)
+		order  = make([]zmath.VecInt, 0, bounds.X*bounds.Y)
+	)
+
+	// Water only flows downhill, so passing it on from the highest cell to the lowest never misses any
+	for x := range m {
+		for y := range m[x] {
+			order = append(order, zmath.VI(x, y))
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return m[order[i].X][order[i].Y] > m[order[j].X][order[j].Y]
+	})
+
+	for _, pos := range order {
+		angle := angles[pos.X][pos.Y]
+		if angle < 0 {
+			continue
+		}
+		var (
+			sector = angle / (math.Pi / 4)
+			first  = int(sector) % 8
+			frac   = sector - math.Floor(sector)
+			water  = acc[pos.X][pos.Y]
+		)
+		if n := pos.Add(Neighbors[first]); acc.ContainsCoord(n) {
+			acc[n.X][n.Y] += water * (1 - frac)
+		}
+		if n := pos.Add(Neighbors[(first+1)%8]); frac > 0 && acc.ContainsCoord(n) {
+			acc[n.X][n.Y] += water * frac
+		}
+	}
+
+	return acc
+}
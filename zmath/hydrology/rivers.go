@@ -0,0 +1,104 @@
+package hydrology
+
+import "github.com/Isarcus/zarks/zmath"
+
+// Watersheds labels every cell by which outlet it drains to, where an outlet is a cell that water doesn't flow
+// out of onto the map, such as a pit or a cell draining off an edge. Labels start from 0, and the number of
+// watersheds is returned too.
+func (d Directions) Watersheds() ([][]int, int) {
+	var (
+		bounds = d.Bounds()
+		labels = make([][]int, bounds.X)
+		count  = 0
+		path   = make([]zmath.VecInt, 0)
+	)
+	for x := range labels {
+		labels[x] = make([]int, bounds.Y)
+		for y := range labels[x] {
+			labels[x][y] = -1
+		}
+	}
+
+	for x := range labels {
+		for y := range labels[x] {
+			// follow the water down until it reaches an outlet or a cell that's already labeled, then label
+			// everything along the way the same
+			path = path[:0]
+			pos := zmath.VI(x, y)
+			for labels[pos.X][pos.Y] == -1 {
+				path = append(path, pos)
+				next, ok := d.Downstream(pos)
+				if !ok {
+					labels[pos.X][pos.Y] = count
+					count++
+					break
+				}
+				pos = next
+			}
+			for _, p := range path {
+				labels[p.X][p.Y] = labels[pos.X][pos.Y]
+			}
+		}
+	}
+
+	return labels, count
+}
+
+// River is a stretch of river running downstream, from a source or a confluence to the next confluence or
+// outlet. Confluences are included at the ends of every river flowing into them, so that they join up.
+type River struct {
+	Points []zmath.Vec // the center of each cell the river flows through
+	Flow   []float64   // the flow accumulation at each point, for drawing wider rivers where more water flows
+}
+
+// Rivers returns every river, made up of all of the cells through which at least threshold cells drain
+// according to acc, which should be the Accumulate of the Directions
+func (d Directions) Rivers(acc zmath.Map, threshold float64) []River {
+	var (
+		bounds   = d.Bounds()
+		upstream = zmath.NewMap(bounds, 0) // how many river cells flow straight into each cell
+		rivers   = make([]River, 0)
+	)
+	for x := range d {
+		for y := range d[x] {
+			if acc[x][y] < threshold {
+				continue
+			}
+			if n, ok := d.Downstream(zmath.VI(x, y)); ok {
+				upstream[n.X][n.Y]++
+			}
+		}
+	}
+
+	// Rivers start from sources, with nothing flowing into them, and from confluences, with several
+	for x := range d {
+		for y := range d[x] {
+			if acc[x][y] < threshold || upstream[x][y] == 1 {
+				continue
+			}
+
+			var (
+				pos   = zmath.VI(x, y)
+				river = River{}
+			)
+			for {
+				river.Points = append(river.Points, zmath.V(float64(pos.X), float64(pos.Y)))
+				river.Flow = append(river.Flow, acc[pos.X][pos.Y])
+
+				next, ok := d.Downstream(pos)
+				if !ok {
+					break
+				}
+				if upstream[next.X][next.Y] > 1 {
+					river.Points = append(river.Points, zmath.V(float64(next.X), float64(next.Y)))
+					river.Flow = append(river.Flow, acc[next.X][next.Y])
+					break
+				}
+				pos = next
+			}
+			rivers = append(rivers, river)
+		}
+	}
+
+	return rivers
+}
@@ -1,31 +1,18 @@
 package zmath
 
 import (
-	"encoding/binary"
 	"fmt"
 	"image"
 	"math"
-	"os"
-
-	"github.com/Isarcus/zarks/system"
-	"github.com/Isarcus/zarks/zmath/zbits"
 )
 
 // Map is a set of 2D raster data, with some helpful member functions
 type Map [][]float64
 
-// NewMap returns a 2D array of float64 of the given bounds, with all cells set to the given initial value
+// NewMap returns a 2D array of float64 of the given bounds, with all cells set to the given initial value.
+// All columns share a single allocation, so the map is as cheap to make as a FlatMap of the same size.
 func NewMap(bounds VecInt, initValue float64) Map {
-	x, y := bounds.X, bounds.Y
-	data := make([][]float64, x)
-	for i := 0; i < x; i++ {
-		data[i] = make([]float64, y)
-		for j := 0; j < y; j++ {
-			data[i][j] = initValue
-		}
-	}
-
-	return data
+	return NewFlatMap(bounds, initValue).ToMap()
 }
 
 // At returns the value of the map at the coordinates specified by the passed VecInt. It does NOT bounds-check!
@@ -43,8 +30,11 @@ func (m Map) PtrTo(pos VecInt) *float64 {
 	return &(m[pos.X][pos.Y])
 }
 
-// Bounds returns the bounds of a map
+// Bounds returns the bounds of a map, or ZVI if it has no columns at all
 func (m Map) Bounds() VecInt {
+	if len(m) == 0 {
+		return ZVI
+	}
 	return VecInt{
 		X: len(m),
 		Y: len(m[0]),
@@ -59,11 +49,11 @@ func (m Map) Area() float64 {
 
 // Clear sets all points on the map equal to the passed value
 func (m Map) Clear(value float64) Map {
-	for x, row := range m {
-		for y := range row {
-			m[x][y] = value
+	m.columns(func(_ int, col []float64) {
+		for y := range col {
+			col[y] = value
 		}
-	}
+	})
 	return m
 }
 
@@ -74,15 +64,7 @@ func (m Map) Zero() Map {
 
 // GetSum returns the sum of all map elements
 func (m Map) GetSum() float64 {
-	sum := 0.0
-
-	for x, row := range m {
-		for y := range row {
-			sum += m[x][y]
-		}
-	}
-
-	return sum
+	return sumColumns(len(m), m.columns)
 }
 
 // GetMean returns the mean of all map elements
@@ -93,49 +75,20 @@ func (m Map) GetMean() float64 {
 
 // GetMin returns the minimum of all map elements
 func (m Map) GetMin() float64 {
-	if m.Bounds().X > 0 && m.Bounds().Y > 0 {
-		min := m[0][0]
-		for _, row := range m {
-			for i := range row {
-				min = math.Min(min, row[i])
-			}
-		}
-
-		return min
-	}
-
-	return 0
+	min, _ := m.GetMinMax()
+	return min
 }
 
 // GetMax returns the maximum of all map elements
 func (m Map) GetMax() float64 {
-	if m.Bounds().X > 0 && m.Bounds().Y > 0 {
-		max := m[0][0]
-		for _, row := range m {
-			for i := range row {
-				max = math.Max(max, row[i])
-			}
-		}
-
-		return max
-	}
-
-	return 0
+	_, max := m.GetMinMax()
+	return max
 }
 
 // GetMinMax returns the min and max of the called Map.
 // This is computationally faster than calling GetMin and GetMax independently.
 func (m Map) GetMinMax() (min, max float64) {
-	if m.Bounds().X > 0 && m.Bounds().Y > 0 {
-		min, max = m[0][0], m[0][0]
-		for _, row := range m {
-			for _, val := range row {
-				min = math.Min(min, val)
-				max = math.Max(max, val)
-			}
-		}
-	}
-	return
+	return minMaxColumns(len(m), m.columns)
 }
 
 // GetRange returns the range of the called Map.
@@ -146,45 +99,41 @@ func (m Map) GetRange() float64 {
 
 // Add the passed value to every datapoint
 func (m Map) Add(addend float64) Map {
-	for x, row := range m {
-		for y := range row {
-			m[x][y] += addend
+	m.columns(func(_ int, col []float64) {
+		for y := range col {
+			col[y] += addend
 		}
-	}
+	})
 	return m
 }
 
 // Subtract the passed value from every datapoint
 func (m Map) Subtract(subtrahend float64) Map {
-	for x, row := range m {
-		for y := range row {
-			m[x][y] -= subtrahend
+	m.columns(func(_ int, col []float64) {
+		for y := range col {
+			col[y] -= subtrahend
 		}
-	}
+	})
 	return m
 }
 
-// ScaleDim scales a map's dimensions by an amount. For example, scaling by 2 will double an image's dimensions.
-// This will lose data when shrinking an image
+// ScaleDim returns a NEW Map with its dimensions scaled by an amount. For example, scaling by 2 will double an
+// image's dimensions. Growing interpolates bilinearly and shrinking averages; use Resize for other filters.
 func (m Map) ScaleDim(by float64) Map {
-	newBounds := m.Bounds().V().Scale(by).VI()
-	newMap := NewMap(newBounds, 0)
-	inv := 1.0 / by
-	for x, row := range newMap {
-		for y := range row {
-			newMap[x][y] = m.At(VI(x, y).V().Scale(inv).VI())
-		}
+	filter := FilterBilinear
+	if by < 1 {
+		filter = FilterArea
 	}
-	return newMap
+	return m.Resize(m.Bounds().V().Scale(by).VI(), filter)
 }
 
 // Multiply every data point by the passed value
 func (m Map) Multiply(multiplicand float64) Map {
-	for x, row := range m {
-		for y := range row {
-			m[x][y] *= multiplicand
+	m.columns(func(_ int, col []float64) {
+		for y := range col {
+			col[y] *= multiplicand
 		}
-	}
+	})
 	return m
 }
 
@@ -194,11 +143,11 @@ func (m Map) AddMap(addend Map) Map {
 		fmt.Println("Bounds don't match")
 		return m
 	}
-	for x, row := range m {
-		for y := range row {
-			m[x][y] += addend[x][y]
+	m.columns(func(x int, col []float64) {
+		for y := range col {
+			col[y] += addend[x][y]
 		}
-	}
+	})
 	return m
 }
 
@@ -207,11 +156,11 @@ func (m Map) SubtractMap(subtrahend Map) Map {
 	if m.Bounds() != subtrahend.Bounds() {
 		return m
 	}
-	for x, row := range m {
-		for y := range row {
-			m[x][y] -= subtrahend[x][y]
+	m.columns(func(x int, col []float64) {
+		for y := range col {
+			col[y] -= subtrahend[x][y]
 		}
-	}
+	})
 	return m
 }
 
@@ -220,21 +169,29 @@ func (m Map) GeometricMean(by Map) Map {
 	if m.Bounds() != by.Bounds() {
 		return m
 	}
-
-	for x, row := range m {
-		for y := range row {
-			m[x][y] = math.Sqrt(math.Abs(m[x][y] * by[x][y]))
+	m.columns(func(x int, col []float64) {
+		for y := range col {
+			col[y] = math.Sqrt(math.Abs(col[y] * by[x][y]))
 		}
-	}
-
+	})
 	return m
 }
 
-// Interpolate interpolates a map between two values
+// Interpolate interpolates a map between two values. A map that's the same everywhere is set to newMin.
 func (m Map) Interpolate(newMin, newMax float64) Map {
-	linear := m.ToLinear()
-	linear.Interpolate(newMin, newMax)
-	linear.To2D(m)
+	var (
+		oldMin, oldMax = m.GetMinMax()
+		oldRange       = oldMax - oldMin
+		newRange       = newMax - newMin
+	)
+	if oldRange == 0 {
+		return m.Clear(newMin)
+	}
+	m.columns(func(_ int, col []float64) {
+		for y := range col {
+			col[y] = ((col[y]-oldMin)/oldRange)*newRange + newMin
+		}
+	})
 	return m
 }
 
@@ -252,117 +209,84 @@ func (m Map) MakeUniform() Map {
 
 // FlipVertical flips the map across its X-axis..
 func (m Map) FlipVertical() Map {
-	for x, row := range m {
-		maxY := m.Bounds().Y - 1
-		for y := range row {
-			if y >= maxY {
-				break
-			}
-			m[x][y], m[x][maxY] = m[x][maxY], m[x][y]
-			maxY--
+	m.columns(func(_ int, col []float64) {
+		for lo, hi := 0, len(col)-1; lo < hi; lo, hi = lo+1, hi-1 {
+			col[lo], col[hi] = col[hi], col[lo]
 		}
-	}
+	})
 	return m
 }
 
 // FlipHorizontal flips the map across its Y-axis.
 func (m Map) FlipHorizontal() Map {
-	maxX := m.Bounds().X - 1
-	for x, row := range m {
-		if x >= maxX {
-			break
-		}
-		for y := range row {
-			m[x][y], m[maxX][y] = m[maxX][y], m[x][y]
+	maxX := len(m) - 1
+	ParallelForN(len(m)/2, workersFor(len(m)*len(m[0])), func(x int) {
+		for y := range m[x] {
+			m[x][y], m[maxX-x][y] = m[maxX-x][y], m[x][y]
 		}
-		maxX--
-	}
+	})
 	return m
 }
 
-// RotateCW90 rotates a map clockwise, 90 degrees. If the called map is square, the underlying array will be used.
-// Otherwise, a new Map will be created and the original discarded.
-// func (m Map) RotateCW90() Map {
-// 	bounds := m.Bounds()
-
-// 	// if square
-// 	if bounds.X == bounds.Y {
-
-// 	} else {
-// 		newBounds := VecInt{
-// 			X: bounds.Y,
-// 			Y: bounds.X,
-// 		}
-// 		rotated := NewMap(newBounds, 0)
-
-// 		for x, row := range m {
-// 			for y := range row {
-// 				rotated[y][x]
-// 			}
-// 		}
-// 	}
-
-// 	return m
-// }
-
 // SetMin sets every value in the map that is less than the passed value TO the passed value.
 // This function does *NOT* interpolate the map between its maximum and a new minimum.
 func (m Map) SetMin(value float64) Map {
-	for x, row := range m {
-		for y := range row {
-			if m[x][y] < value {
-				m[x][y] = value
+	m.columns(func(_ int, col []float64) {
+		for y := range col {
+			if col[y] < value {
+				col[y] = value
 			}
 		}
-	}
+	})
 	return m
 }
 
 // SetMax sets every value in the map that is greater than the passed value TO the passed value.
 // This function does *NOT* interpolate the map between its minimum and a new maximum.
 func (m Map) SetMax(value float64) Map {
-	for x, row := range m {
-		for y := range row {
-			if m[x][y] > value {
-				m[x][y] = value
+	m.columns(func(_ int, col []float64) {
+		for y := range col {
+			if col[y] > value {
+				col[y] = value
 			}
 		}
-	}
+	})
 	return m
 }
 
 // Replace replaces all occurrences of a value with another value
 func (m Map) Replace(value, with float64) Map {
-	for x, row := range m {
-		for y := range row {
-			if m[x][y] == value {
-				m[x][y] = with
+	m.columns(func(_ int, col []float64) {
+		for y := range col {
+			if col[y] == value {
+				col[y] = with
 			}
 		}
-	}
+	})
 	return m
 }
 
 // ReplaceNot replaces all data that is NOT equal to the passed value with another value
 func (m Map) ReplaceNot(value, with float64) Map {
-	for x, row := range m {
-		for y := range row {
-			if m[x][y] != value {
-				m[x][y] = with
+	m.columns(func(_ int, col []float64) {
+		for y := range col {
+			if col[y] != value {
+				col[y] = with
 			}
 		}
-	}
+	})
 	return m
 }
 
 // CustomMod lets you pass in ANY function that takes in a float64 and returns a float64, which it will
 // then use to modify every value of the called map.
+// Large maps are modified in parallel, so modFunc must be safe to call from several goroutines at once.
 func (m Map) CustomMod(modFunc func(float64) float64) Map {
-	for x, row := range m {
-		for y := range row {
-			m[x][y] = modFunc(m[x][y])
+	m.columns(func(_ int, col []float64) {
+		for y := range col {
+			col[y] = modFunc(col[y])
 		}
-	}
+	})
 	return m
 }
 
@@ -391,17 +315,17 @@ func (m Map) Copy(min, max VecInt) Map {
 	}
 
 	copyMap := NewMap(dim, 0)
-	for x := 0; x < dim.X; x++ {
-		for y := 0; y < dim.Y; y++ {
+	copyMap.columns(func(x int, col []float64) {
+		for y := range col {
 			pos := VecInt{
 				X: min.X + x,
 				Y: min.Y + y,
 			}
 			if m.ContainsCoord(pos) {
-				copyMap[x][y] = m.At(pos)
+				col[y] = m.At(pos)
 			}
 		}
-	}
+	})
 
 	return copyMap
 }
@@ -409,7 +333,11 @@ func (m Map) Copy(min, max VecInt) Map {
 // CopyAll deepcopies an entire map and returns the copy.
 // Equivalent to calling the Copy() function for a map's entire area.
 func (m Map) CopyAll() Map {
-	return m.Copy(ZVI, m.Bounds())
+	copyMap := NewMap(m.Bounds(), 0)
+	copyMap.columns(func(x int, col []float64) {
+		copy(col, m[x])
+	})
+	return copyMap
 }
 
 // Paste pastes all of the data from pasteMe into a map, starting at the specified coordinate in the called map.
@@ -430,43 +358,9 @@ func (m Map) Paste(pasteMe Map, at VecInt) Map {
 	return m
 }
 
-// BlurGaussian blurs gaussly (it looks nice)
+// BlurGaussian blurs gaussly (it looks nice). See Blur for more control over how.
 func (m Map) BlurGaussian(radius int) Map {
-	// For later
-	var (
-		circle     = GetCircleCoords(radius)
-		bounds     = m.Bounds()
-		sigma2     = math.Pow(float64(radius)/3.0, 2)
-		gaussCoeff = 1.0 / (2.0 * math.Pi * sigma2)
-	)
-
-	blurMap := NewMap(bounds, 0)
-
-	for x := 0; x < bounds.X; x++ {
-		for y := 0; y < bounds.Y; y++ {
-			var weightSum, trueWeightSum, sum float64
-			pos := VecInt{
-				X: x,
-				Y: y,
-			}
-
-			for _, cpt := range circle {
-				blurPos := pos.Add(cpt)
-				weight := math.Pow(math.E, -float64(cpt.X*cpt.X+cpt.Y*cpt.Y)/(2.0*sigma2))
-				trueWeightSum += weight
-				if m.ContainsCoord(blurPos) {
-					weightSum += weight
-					sum += weight * m.At(blurPos)
-				}
-			}
-
-			ratio := gaussCoeff * trueWeightSum / weightSum
-			sum *= ratio
-			blurMap[x][y] = sum
-		}
-	}
-	m.Paste(blurMap, ZVI)
-	return m
+	return m.Blur(NewBlur(radius))
 }
 
 // ToLinear converts a Map to a Set
@@ -573,12 +467,11 @@ func (m Map) SlopeAt(pos VecInt) float64 {
 // GetSlopeMap returns a NEW map of slopes.
 func (m Map) GetSlopeMap() Map {
 	slopeMap := NewMap(m.Bounds(), 0)
-
-	for x, row := range m {
-		for y := range row {
-			slopeMap[x][y] = m.SlopeAt(VecInt{x, y})
+	slopeMap.columns(func(x int, col []float64) {
+		for y := range col {
+			col[y] = m.SlopeAt(VecInt{x, y})
 		}
-	}
+	})
 
 	return slopeMap
 }
@@ -624,62 +517,6 @@ func MapFromImage(img *image.RGBA, color Color) Map {
 // ImageToMap returns a map of the R, G, B, or brightness values of an image
 var ImageToMap = MapFromImage
 
-// Save saves a Map as binary data at the path specified. File ending should be .zmap
-func (m Map) Save(path string) {
-	f := system.CreateFile(path)
-	defer f.Close()
-
-	// Header (64)
-	header := [64]byte{}
-	system.WriteBytes(f, header[:])
-
-	// bounds (8)
-	var dimX = uint32(m.Bounds().X)
-	var dimY = uint32(m.Bounds().Y)
-	system.WriteBytes(f, zbits.Uint32ToBytes(dimX, zbits.LE))
-	system.WriteBytes(f, zbits.Uint32ToBytes(dimY, zbits.LE))
-
-	// Data (8n)
-	for x, row := range m {
-		for y := range row {
-			bits := math.Float64bits(m[x][y])
-			system.WriteBytes(f, zbits.Uint64ToBytes(bits, zbits.LE))
-		}
-	}
-}
-
-// MapFromPath loads a Map from the target path
-func MapFromPath(path string) Map {
-	f, err := os.Open(path)
-	if err != nil {
-		fmt.Println(err)
-		return Map{{}}
-	}
-
-	// Header (64)
-	hdr := [64]byte{}
-	f.Read(hdr[:])
-
-	// Bounds (8)
-	bounds := [8]byte{}
-	f.Read(bounds[:])
-
-	dimX := binary.LittleEndian.Uint32(bounds[0:4])
-	dimY := binary.LittleEndian.Uint32(bounds[4:8])
-	newMap := NewMap(VI(int(dimX), int(dimY)), 0)
-
-	// Data (8n)
-	for x, row := range newMap {
-		for y := range row {
-			bytes := [8]byte{}
-			f.Read(bytes[:])
-			newMap[x][y] = math.Float64frombits(binary.LittleEndian.Uint64(bytes[:]))
-		}
-	}
-
-	return newMap
-}
-
 //                    //
 // - - - MAPVEC - - - //
 //                    //
@@ -0,0 +1,104 @@
+package noise
+
+import (
+	"context"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// NoiseType is a kind of noise, for choosing which one to generate through a Config
+type NoiseType int
+
+// Noise types
+const (
+	SimplexNoise NoiseType = iota
+	PerlinNoise
+	WorleyNoise
+	ValueNoise
+	OpenSimplex2Noise
+	ImprovedPerlinNoise
+)
+
+// NewSampler returns a new Sampler of the given type. Unknown types return Simplex samplers.
+func NewSampler(t NoiseType, seed int64) Sampler {
+	switch t {
+	case PerlinNoise:
+		return NewPerlin(seed)
+	case WorleyNoise:
+		return NewWorley(seed)
+	case ValueNoise:
+		return NewValue(seed)
+	case OpenSimplex2Noise:
+		return NewOpenSimplex2(seed)
+	case ImprovedPerlinNoise:
+		return NewImprovedPerlin(seed)
+	default:
+		return NewSimplex(seed)
+	}
+}
+
+// NewMap generates a new noise map of whichever type cfg.Type specifies
+func NewMap(cfg Config) zmath.Map {
+	m, _, _ := GenerateMap(context.Background(), cfg)
+	return m
+}
+
+// GenerateMap generates a new noise map of whichever type cfg.Type specifies, along with the seed that was used,
+// which is only interesting if cfg.Seed was left 0 for a random one. If ctx is cancelled before the map is
+// finished, GenerateMap stops early and returns ctx's error.
+func GenerateMap(ctx context.Context, cfg Config) (zmath.Map, int64, error) {
+	cfg.checkDefaults()
+	return cfg.generate(ctx, nil)
+}
+
+// NewMapDeriv generates a new noise map of whichever type cfg.Type specifies, along with its gradient
+// (dN/dx, dN/dy) at every pixel
+func NewMapDeriv(cfg Config) (zmath.Map, zmath.MapVec) {
+	m, derivs, _, _ := GenerateMapDeriv(context.Background(), cfg)
+	return m, derivs
+}
+
+// GenerateMapDeriv is GenerateMap, but also returns the gradient of the map at every pixel, measured per pixel.
+// Gradients come straight from the noise rather than from neighboring pixels, so they are exact (for noise types
+// that support it) right up to the edges of the map, and line up across chunks.
+func GenerateMapDeriv(ctx context.Context, cfg Config) (zmath.Map, zmath.MapVec, int64, error) {
+	cfg.checkDefaults()
+	derivs := zmath.NewMapVec(cfg.Dimensions)
+	m, seed, err := cfg.generate(ctx, derivs)
+	if m == nil {
+		derivs = nil
+	}
+	return m, derivs, seed, err
+}
+
+// generate sums the octaves of a map, filling in derivs too if it isn't nil
+func (cfg Config) generate(ctx context.Context, derivs zmath.MapVec) (zmath.Map, int64, error) {
+	tr := newTracker(ctx, cfg.Progress, cfg.Dimensions.X)
+
+	// Perlin maps have always started an octave smaller than the rest
+	first := 0
+	if cfg.Type == PerlinNoise {
+		first = 1
+	}
+	m := sumOctaves(tr, cfg, first, derivs, func(seed int64, _ float64) Sampler {
+		return cfg.newSampler(seed)
+	})
+
+	return m, cfg.Seed, tr.err()
+}
+
+// newSampler returns a new Sampler of the type cfg.Type specifies, with any of its type-specific settings
+func (cfg Config) newSampler(seed int64) Sampler {
+	switch s := NewSampler(cfg.Type, seed).(type) {
+	case *Simplex:
+		s.R = cfg.R
+		return s
+	case *Worley:
+		s.Metric = cfg.Metric
+		s.Feature = cfg.Feature
+		s.N = cfg.N
+		return s
+	default:
+		return s
+	}
+}
@@ -1,36 +1,60 @@
 package noise
 
 import (
-	"fmt"
-	"math/rand"
 	"time"
 
 	"github.com/Isarcus/zarks/zmath"
 )
 
 // Config contains all of the necessary information to generate a noise map.
-// Set seed to 0 for a random seed
+// Set seed to 0 for a random seed; GenerateMap returns the seed that ended up being used.
 type Config struct {
+	Type           NoiseType    // which kind of noise NewMap generates
 	Dimensions     zmath.VecInt // size of image
 	Octaves        int          // number of octaves to iterate
+	Lacunarity     float64      // how many times smaller each octave's boxes are than the last's
+	Persistence    float64      // how much each octave is weighted compared to the last
+	Fractal        FractalType  // how octaves are combined
 	Normalize      bool         // whether to normalize data between [0,1] after generation
-	Seed           int64        // what to seed the random number generator with. 0 for random seed!
+	Seed           int64        // what to seed the noise with. 0 for random seed!
 	BoxSizeInitial float64      // Initial size of box or triangle in pixels
 
-	R float64 // Simplex only - size of radius of influence for vector
-	N int     // Worley only - how many nearest points to include in calculations
+	// Offset is where the map's first cell lies in world space, in pixels. Maps generated with the same seed at
+	// different offsets line up seamlessly, as long as Normalize is off.
+	Offset zmath.Vec
+	// Wrap makes the map tile seamlessly with itself. Box sizes are adjusted slightly so that a whole number of
+	// boxes fits across each dimension. Not supported by Layerplex or Stratoplex.
+	Wrap bool
+
+	WarpStrength   float64 // how far to displace coordinates by before sampling, in boxes. 0 for no warping
+	WarpIterations int     // how many times to displace coordinates, each time by the noise at the last result
+
+	R       float64 // Simplex only - size of radius of influence for vector
+	N       int     // Worley only - which nearest point to measure the distance to, for the FN feature
+	Metric  Metric  // Worley only - how distance to points is measured
+	Feature Feature // Worley only - what to output
+	Beta    float64 // Spectral only - how steeply the spectrum falls off, as 1/f^Beta. 0 is white noise
+
+	// Progress, if set, is called every time another part of the map is finished, with how many parts are done
+	// out of how many there are in total. It's never called by more than one goroutine at a time.
+	Progress func(done, total int)
 }
 
 // DefaultConfig will give you a randomly seeded, 512x512 image with 4 octaves.
 var DefaultConfig = Config{
+	Type:           SimplexNoise,
 	Dimensions:     zmath.VI(512, 512),
 	Octaves:        4,
+	Lacunarity:     2,
+	Persistence:    0.5,
+	Fractal:        FBM,
 	Seed:           0,
 	BoxSizeInitial: 256,
 	Normalize:      true,
 
-	R: 0.6,
-	N: 3,
+	R:    0.6,
+	N:    3,
+	Beta: 2,
 }
 
 func (cfg *Config) checkDefaults() {
@@ -41,12 +65,17 @@ func (cfg *Config) checkDefaults() {
 	if cfg.Octaves == 0 {
 		cfg.Octaves = DefaultConfig.Octaves
 	}
+	if cfg.Lacunarity == 0 {
+		cfg.Lacunarity = DefaultConfig.Lacunarity
+	}
+	if cfg.Persistence == 0 {
+		cfg.Persistence = DefaultConfig.Persistence
+	}
+	if cfg.WarpIterations == 0 {
+		cfg.WarpIterations = 1
+	}
 	if cfg.Seed == 0 {
-		seed := time.Now().Unix()
-		rand.Seed(seed)
-		fmt.Println("Using random seed: ", seed)
-	} else {
-		rand.Seed(cfg.Seed)
+		cfg.Seed = time.Now().UnixNano()
 	}
 	if cfg.BoxSizeInitial == 0 {
 		cfg.BoxSizeInitial = DefaultConfig.BoxSizeInitial
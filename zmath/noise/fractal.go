@@ -0,0 +1,257 @@
+package noise
+
+import (
+	"math"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// FractalType is a way of combining several octaves of noise
+type FractalType int
+
+// Fractal types
+const (
+	FBM              FractalType = iota // fractional Brownian motion: a plain weighted sum
+	Billow                              // like FBM, but with the absolute value of each octave, for puffy shapes
+	RidgedMulti                         // ridged multifractal: sharp ridges whose detail builds up along the ridges
+	HybridMulti                         // hybrid multifractal: smooth valleys and rough peaks
+	DerivativeDamped                    // like FBM, but each octave is damped by how steep the octaves before it were
+)
+
+// Fractal is a Sampler that combines several octaves of other Samplers. Octave i is sampled at Lacunarity^i
+// times the frequency of the first octave, and is weighted by Persistence^i.
+type Fractal struct {
+	Type                    FractalType
+	Octaves                 []Sampler // usually all of the same kind of noise, but with different seeds
+	Lacunarity, Persistence float64
+
+	Offset, Gain float64 // RidgedMulti and HybridMulti only; NewFractal picks sensible defaults
+}
+
+// NewFractal returns a Fractal of the given type over the passed octaves, with the default Lacunarity and
+// Persistence
+func NewFractal(t FractalType, octaves ...Sampler) *Fractal {
+	f := &Fractal{
+		Type:        t,
+		Octaves:     octaves,
+		Lacunarity:  DefaultConfig.Lacunarity,
+		Persistence: DefaultConfig.Persistence,
+		Gain:        2,
+	}
+	switch t {
+	case RidgedMulti:
+		f.Offset = 1
+	case HybridMulti:
+		f.Offset = 0.7
+	}
+	return f
+}
+
+// NewFractalFrom returns a Fractal of the given type with the number of octaves specified, each one made by
+// passing a different seed (derived from the one passed) to newSampler
+func NewFractalFrom(t FractalType, octaves int, seed int64, newSampler func(seed int64) Sampler) *Fractal {
+	samplers := make([]Sampler, octaves)
+	for oct := range samplers {
+		samplers[oct] = newSampler(octaveSeed(seed, oct))
+	}
+	return NewFractal(t, samplers...)
+}
+
+// Eval2D returns the combined value of every octave at (x, y)
+func (f *Fractal) Eval2D(x, y float64) float64 {
+	val, _ := f.eval(x, y, f.Type == DerivativeDamped)
+	return val
+}
+
+// EvalDeriv2D returns the combined value of every octave at (x, y), along with its gradient. The gradient is
+// exact if every octave is a DerivSampler, except for DerivativeDamped fractals: their damping changes with the
+// second derivatives of the octaves, which aren't available, so their gradient is estimated by differences.
+func (f *Fractal) EvalDeriv2D(x, y float64) (float64, zmath.Vec) {
+	if f.Type == DerivativeDamped {
+		return diffDeriv(f, x, y)
+	}
+	return f.eval(x, y, true)
+}
+
+// eval combines every octave at (x, y). Gradients are only computed if deriv is set.
+func (f *Fractal) eval(x, y float64, deriv bool) (float64, zmath.Vec) {
+	var (
+		sum    = 0.0
+		weight = 1.0
+		amp    = 1.0
+		freq   = 1.0
+
+		dSum, dWeight zmath.Vec
+		dTotal        zmath.Vec // sum of each octave's gradient on its own lattice, for DerivativeDamped
+	)
+	for i, oct := range f.Octaves {
+		var (
+			val  float64
+			dOct zmath.Vec
+		)
+		if deriv {
+			val, dOct = evalDeriv(oct, x*freq, y*freq)
+		} else {
+			val = oct.Eval2D(x*freq, y*freq)
+		}
+		dVal := dOct.Scale(freq)
+
+		switch f.Type {
+		case Billow:
+			sum += (2*math.Abs(val) - 1) * amp
+			dSum = dSum.Add(dVal.Scale(2 * sign(val) * amp))
+		case RidgedMulti:
+			// each octave is only as strong as the one before it was sharp
+			var (
+				sharp   = f.Offset - math.Abs(val)
+				signal  = sharp * sharp * weight
+				dSignal = dVal.Scale(-2 * sharp * sign(val) * weight).Add(dWeight.Scale(sharp * sharp))
+			)
+			weight = zmath.MinMax(0, 1, signal*f.Gain)
+			if weight > 0 && weight < 1 {
+				dWeight = dSignal.Scale(f.Gain)
+			} else {
+				dWeight = zmath.ZV
+			}
+			sum += signal * amp
+			dSum = dSum.Add(dSignal.Scale(amp))
+		case HybridMulti:
+			// each octave is only as strong as every octave before it was high
+			signal, dSignal := (val+f.Offset)*amp, dVal.Scale(amp)
+			if i == 0 {
+				sum, weight = signal, signal
+				dSum, dWeight = dSignal, dSignal
+			} else {
+				if weight > 1 {
+					weight, dWeight = 1, zmath.ZV
+				}
+				sum += weight * signal
+				dSum = dSum.Add(dWeight.Scale(signal)).Add(dSignal.Scale(weight))
+				dWeight = dWeight.Scale(signal).Add(dSignal.Scale(weight))
+				weight *= signal
+			}
+		case DerivativeDamped:
+			// steep slopes so far smooth out every octave after, like erosion would
+			dTotal = dTotal.Add(dOct)
+			sum += val * amp / (1 + dTotal.Dot(dTotal))
+		default:
+			sum += val * amp
+			dSum = dSum.Add(dVal.Scale(amp))
+		}
+
+		amp *= f.Persistence
+		freq *= f.Lacunarity
+	}
+	return sum, dSum
+}
+
+// sign returns -1 for negative numbers, and 1 otherwise
+func sign(f float64) float64 {
+	if f < 0 {
+		return -1
+	}
+	return 1
+}
+
+// scaled samples another Sampler at a different scale along each axis
+type scaled struct {
+	Sampler
+	x, y float64
+}
+
+func (s scaled) Eval2D(x, y float64) float64 {
+	return s.Sampler.Eval2D(x*s.x, y*s.y)
+}
+
+func (s scaled) EvalDeriv2D(x, y float64) (float64, zmath.Vec) {
+	val, deriv := evalDeriv(s.Sampler, x*s.x, y*s.y)
+	return val, zmath.V(deriv.X*s.x, deriv.Y*s.y)
+}
+
+// sumOctaves returns a new Map holding cfg.Octaves octaves of noise, combined according to cfg.Fractal.
+// Octave n is sampled from a lattice Lacunarity^(n+first) times smaller than cfg.BoxSizeInitial and is weighted
+// by Persistence^(n+first). newSampler is passed the seed and box size of each octave.
+// If derivs isn't nil, it must be the same size as the map, and is filled with the gradient of the map per pixel.
+// Columns are computed in parallel, which is safe since each Sampler only ever reads its own tables. Each column
+// is reported to tr as it finishes; if tr is cancelled, the rest are skipped and nil is returned.
+func sumOctaves(tr *tracker, cfg Config, first int, derivs zmath.MapVec, newSampler func(seed int64, boxSize float64) Sampler) zmath.Map {
+	var (
+		noiseMap = zmath.NewMap(cfg.Dimensions, 0)
+		boxSize  = cfg.BoxSizeInitial / math.Pow(cfg.Lacunarity, float64(first))
+		weight   = math.Pow(cfg.Persistence, float64(first))
+		sampler  = Sampler(cfg.fractal(cfg.Fractal, cfg.Seed, boxSize, newSampler))
+	)
+	if cfg.WarpStrength != 0 {
+		sampler = cfg.warp(sampler, boxSize)
+	}
+
+	zmath.ParallelFor(cfg.Dimensions.X, func(x int) {
+		if tr.cancelled() {
+			return
+		}
+		var (
+			col = noiseMap[x]
+			ptX = (float64(x) + cfg.Offset.X) / boxSize
+		)
+		if derivs == nil {
+			for y := range col {
+				col[y] = sampler.Eval2D(ptX, (float64(y)+cfg.Offset.Y)/boxSize) * weight
+			}
+		} else {
+			for y := range col {
+				val, deriv := evalDeriv(sampler, ptX, (float64(y)+cfg.Offset.Y)/boxSize)
+				col[y] = val * weight
+				derivs[x][y] = deriv.Scale(weight / boxSize)
+			}
+		}
+		tr.step()
+	})
+
+	if tr.cancelled() {
+		return nil
+	}
+	if cfg.Normalize {
+		// stretching the map to [0, 1] stretches its slopes by the same amount
+		if min, max := noiseMap.GetMinMax(); derivs != nil && max > min {
+			for _, col := range derivs {
+				for y := range col {
+					col[y] = col[y].Scale(1 / (max - min))
+				}
+			}
+		}
+		noiseMap.Interpolate(0, 1)
+	}
+
+	return noiseMap
+}
+
+// fractal returns cfg.Octaves octaves of noise, combined in the way specified, for a map whose first octave's
+// boxes are the given size
+func (cfg Config) fractal(t FractalType, seed int64, boxSize float64, newSampler func(seed int64, boxSize float64) Sampler) *Fractal {
+	fractal := NewFractal(t)
+	fractal.Lacunarity = cfg.Lacunarity
+	fractal.Persistence = cfg.Persistence
+
+	for oct := 0; oct < cfg.Octaves; oct++ {
+		var (
+			octBoxSize = boxSize / math.Pow(cfg.Lacunarity, float64(oct))
+			sampler    = newSampler(octaveSeed(seed, oct), octBoxSize)
+		)
+		if cfg.Wrap {
+			sampler = wrapOctave(sampler, cfg.Dimensions, octBoxSize)
+		}
+		fractal.Octaves = append(fractal.Octaves, sampler)
+	}
+	return fractal
+}
+
+// wrapOctave makes an octave of noise sampled from a lattice of the given box size repeat every dim pixels. The
+// octave is stretched slightly so that a whole number of boxes fits across each dimension.
+func wrapOctave(s Sampler, dim zmath.VecInt, boxSize float64) Sampler {
+	snapped, boxes := wrapBoxes(dim, boxSize)
+	return scaled{
+		Sampler: Tile(s, boxes.X, boxes.Y),
+		x:       boxSize / snapped.X,
+		y:       boxSize / snapped.Y,
+	}
+}
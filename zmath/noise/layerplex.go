@@ -1,10 +1,9 @@
 package noise
 
 import (
-	"fmt"
+	"context"
 	"image"
 	"math"
-	"math/rand"
 
 	"github.com/Isarcus/zarks/zimg"
 	"github.com/Isarcus/zarks/zmath"
@@ -12,8 +11,17 @@ import (
 
 // NewLayerplexMap returns a newly generated simplex map, with a slightly modified algorithm.
 // The data within 'layer' is used to modify the magnitude of the simplex vectors, for an interesting effect!
+// Progress is reported once per octave.
 func NewLayerplexMap(cfg Config, layer zmath.Map) zmath.Map {
+	m, _, _ := GenerateLayerplexMap(context.Background(), cfg, layer)
+	return m
+}
+
+// GenerateLayerplexMap is NewLayerplexMap, but also returns the seed that was used, and stops early with ctx's
+// error if ctx is cancelled
+func GenerateLayerplexMap(ctx context.Context, cfg Config, layer zmath.Map) (zmath.Map, int64, error) {
 	cfg.checkDefaults()
+	tr := newTracker(ctx, cfg.Progress, cfg.Octaves)
 	r2 := cfg.R * cfg.R
 	layerplexMap := zmath.NewMap(cfg.Dimensions, 0)
 
@@ -23,15 +31,19 @@ func NewLayerplexMap(cfg Config, layer zmath.Map) zmath.Map {
 	)
 
 	for oct := 0.0; int(oct) < cfg.Octaves; oct++ {
-		// create a new hashmap for angles
-		vecMap := make(map[string]float64)
-		octInfluence := math.Pow(0.5, oct)
+		// every octave gets its own set of vectors
+		tbl := newPermTable(octaveSeed(cfg.Seed, int(oct)))
+		octInfluence := math.Pow(cfg.Persistence, oct)
+		boxSize := cfg.BoxSizeInitial / math.Pow(cfg.Lacunarity, oct)
 
 		// dive in
 		for x := 0; x < cfg.Dimensions.X; x++ {
+			if tr.cancelled() {
+				return nil, cfg.Seed, tr.err()
+			}
 			for y := 0; y < cfg.Dimensions.Y; y++ {
-				iptX := float64(x) / (cfg.BoxSizeInitial * octInfluence)
-				iptY := float64(y) / (cfg.BoxSizeInitial * octInfluence)
+				iptX := (float64(x) + cfg.Offset.X) / boxSize
+				iptY := (float64(y) + cfg.Offset.Y) / boxSize
 				ipt := zmath.V(iptX, iptY)
 
 				layerX := int(float64(x) * conv.X)
@@ -54,14 +66,8 @@ func NewLayerplexMap(cfg Config, layer zmath.Map) zmath.Map {
 
 				// get vectors of the three corners
 				for i := 0; i < 3; i++ {
-					key := getKey(corners[i].VI())
-					if angle, ok := vecMap[key]; ok { // if random angle already determined, use it
-						vectors[i] = zmath.V(math.Cos(angle), math.Sin(angle)).Scale(layer[layerX][layerY])
-					} else { // otherwise, generate one for this and future use
-						angle = rand.Float64() * 2.0 * math.Pi
-						vectors[i] = zmath.V(math.Cos(angle), math.Sin(angle)).Scale(layer[layerX][layerY])
-						vecMap[key] = angle
-					}
+					corner := corners[i].VI()
+					vectors[i] = tbl.grad2(corner.X, corner.Y).Scale(layer[layerX][layerY])
 				}
 
 				// determine distance to each unskewed corner
@@ -81,19 +87,28 @@ func NewLayerplexMap(cfg Config, layer zmath.Map) zmath.Map {
 				layerplexMap[x][y] += Z * octInfluence
 			}
 		}
-		fmt.Println("Octave ", oct+1, " finished.")
+		tr.step()
 	}
 
 	if cfg.Normalize {
 		layerplexMap.Interpolate(0, 1)
 	}
 
-	return layerplexMap
+	return layerplexMap, cfg.Seed, nil
 }
 
 // NewStratoplexMap returns the result of some very cool magic
+// Progress is reported once per octave.
 func NewStratoplexMap(cfg Config, img *image.RGBA) *zimg.ZImage {
+	zi, _, _ := GenerateStratoplexMap(context.Background(), cfg, img)
+	return zi
+}
+
+// GenerateStratoplexMap is NewStratoplexMap, but also returns the seed in cfg, and stops early with ctx's error
+// if ctx is cancelled. Stratoplex is drawn entirely from img, so the seed doesn't change anything about it.
+func GenerateStratoplexMap(ctx context.Context, cfg Config, img *image.RGBA) (*zimg.ZImage, int64, error) {
 	cfg.checkDefaults()
+	tr := newTracker(ctx, cfg.Progress, cfg.Octaves)
 	cfg.Dimensions = zmath.VI(img.Rect.Dx(), img.Rect.Dy())
 	r2 := cfg.R * cfg.R
 
@@ -103,13 +118,16 @@ func NewStratoplexMap(cfg Config, img *image.RGBA) *zimg.ZImage {
 	)
 
 	for oct := 0.0; oct < float64(cfg.Octaves); oct++ {
-		octInfluence := math.Pow(0.5, oct)
+		boxSize := cfg.BoxSizeInitial / math.Pow(cfg.Lacunarity, oct)
 
 		for x := 0; x < cfg.Dimensions.X; x++ {
+			if tr.cancelled() {
+				return nil, cfg.Seed, tr.err()
+			}
 			for y := 0; y < cfg.Dimensions.Y; y++ {
 				ipt := zmath.V(
-					float64(x)/(cfg.BoxSizeInitial*octInfluence),
-					float64(y)/(cfg.BoxSizeInitial*octInfluence),
+					float64(x)/boxSize,
+					float64(y)/boxSize,
 				)
 
 				// skew input coordinates
@@ -164,10 +182,10 @@ func NewStratoplexMap(cfg Config, img *image.RGBA) *zimg.ZImage {
 				}
 			}
 		}
-		fmt.Println("Octave ", oct, " finished.")
+		tr.step()
 	}
 
 	zi.Interpolate(0, 255)
 	zi.MakeOpaque()
-	return zi
+	return zi, cfg.Seed, nil
 }
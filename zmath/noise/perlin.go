@@ -1,100 +1,137 @@
 package noise
 
 import (
-	"fmt"
 	"math"
-	"math/rand"
 
 	"github.com/Isarcus/zarks/zmath"
 )
 
-// NewPerlinMap generates a new perlin noise map according to the specified configuration.
-func NewPerlinMap(cfg Config) zmath.Map {
-	cfg.checkDefaults()
-	perlinMap := zmath.NewMap(cfg.Dimensions, 0)
-
-	var cornerVecs = [4]zmath.Vec{
-		zmath.V(0, 0),
-		zmath.V(1, 0),
-		zmath.V(1, 1),
-		zmath.V(0, 1),
+// Perlin is a seeded perlin noise sampler. It doesn't allocate when sampled and is safe to use from multiple
+// goroutines at once.
+type Perlin struct {
+	// If nonzero, the noise repeats every PeriodX units along x and every PeriodY units along y
+	PeriodX, PeriodY int
+
+	tbl      permTable
+	improved bool // whether to use the 12 fixed gradients of improved perlin noise
+}
+
+// grad12 holds the gradients of Ken Perlin's improved noise: the midpoints of the edges of a cube. In 2D, only
+// their x and y components matter.
+var grad12 = [12]zmath.Vec{
+	{X: 1, Y: 1}, {X: -1, Y: 1}, {X: 1, Y: -1}, {X: -1, Y: -1},
+	{X: 1, Y: 0}, {X: -1, Y: 0}, {X: 1, Y: 0}, {X: -1, Y: 0},
+	{X: 0, Y: 1}, {X: 0, Y: -1}, {X: 0, Y: 1}, {X: 0, Y: -1},
+}
+
+// NewPerlin returns a new Perlin sampler. The same seed always produces the same noise.
+func NewPerlin(seed int64) *Perlin {
+	return &Perlin{
+		tbl: newPermTable(seed),
 	}
+}
+
+// NewImprovedPerlin returns a new Perlin sampler that picks each corner's gradient from a fixed table of 12,
+// rather than from every direction. This is faster to look up and gives the noise a slightly more angular look.
+func NewImprovedPerlin(seed int64) *Perlin {
+	p := NewPerlin(seed)
+	p.improved = true
+	return p
+}
 
-	// The Loop
-	for oct := 1.0; oct <= float64(cfg.Octaves); oct++ {
-		boxSize := cfg.BoxSizeInitial / math.Pow(2, oct)
-		boxesX := math.Ceil(float64(cfg.Dimensions.X)/boxSize) + 1
-		boxesY := math.Ceil(float64(cfg.Dimensions.Y)/boxSize) + 1
-		vectors := zmath.NewMapVec(zmath.VI(int(boxesX+1), int(boxesY+1)))
-
-		for bx := 0; bx < int(boxesX); bx++ {
-			for by := 0; by < int(boxesY); by++ {
-				// Generate random vectors
-				for _, cornerVec := range cornerVecs {
-					if vectors[bx+int(cornerVec.X)][by+int(cornerVec.Y)] == zmath.ZV {
-						theta := 2.0 * rand.Float64() * math.Pi
-						vectors[bx+int(cornerVec.X)][by+int(cornerVec.Y)] = zmath.Vec{
-							X: math.Cos(theta),
-							Y: math.Sin(theta),
-						}
-						//fmt.Println(theta)
-					}
-				}
-
-				// Calculate current box size
-				boxStart := zmath.Vec{
-					X: math.Floor(float64(bx) * boxSize),
-					Y: math.Floor(float64(by) * boxSize),
-				}
-				boxSize := zmath.Vec{
-					X: math.Floor(float64(bx+1)*boxSize) - boxStart.X,
-					Y: math.Floor(float64(by+1)*boxSize) - boxStart.Y,
-				}
-
-				// Loop within one box
-				for dx := 0.0; dx < boxSize.X; dx++ {
-					dataX := int(boxStart.X + dx)
-					if dataX < 0 || dataX >= cfg.Dimensions.X {
-						continue
-					}
-					for dy := 0.0; dy < boxSize.Y; dy++ {
-						dataY := int(boxStart.Y + dy)
-						if dataY < 0 || dataY >= cfg.Dimensions.Y {
-							continue
-						}
-
-						boxPos := zmath.Vec{
-							X: dx / boxSize.X,
-							Y: dy / boxSize.Y,
-						}
-
-						dot00 := boxPos.Subtract(cornerVecs[0]).Dot(vectors[bx][by])
-						dot10 := boxPos.Subtract(cornerVecs[1]).Dot(vectors[bx+1][by])
-						dot11 := boxPos.Subtract(cornerVecs[2]).Dot(vectors[bx+1][by+1])
-						dot01 := boxPos.Subtract(cornerVecs[3]).Dot(vectors[bx][by+1])
-
-						x0 := interpolatePow5(dot00, dot10, boxPos.X)
-						x1 := interpolatePow5(dot01, dot11, boxPos.X)
-
-						Z := interpolatePow5(x0, x1, boxPos.Y)
-
-						perlinMap[dataX][dataY] += Z * math.Pow(0.5, oct)
-					}
-				}
-			}
-		}
-		fmt.Println("Octave ", oct, " finished.")
+// Eval2D returns the value of the noise at (x, y), where a distance of 1 is the size of one box.
+// Values fall within [-1, 1].
+func (p *Perlin) Eval2D(x, y float64) float64 {
+	val, _ := p.EvalDeriv2D(x, y)
+	return val
+}
+
+// EvalDeriv2D returns the value of the noise at (x, y), along with its exact gradient there
+func (p *Perlin) EvalDeriv2D(x, y float64) (float64, zmath.Vec) {
+	var (
+		boxX, boxY = math.Floor(x), math.Floor(y)
+		ix, iy     = int(boxX), int(boxY)
+		fx, fy     = x - boxX, y - boxY // position within the box
+	)
+
+	var (
+		ix0, ix1 = wrapInt(ix, p.PeriodX), wrapInt(ix+1, p.PeriodX)
+		iy0, iy1 = wrapInt(iy, p.PeriodY), wrapInt(iy+1, p.PeriodY)
+	)
+
+	var (
+		g00 = p.grad(ix0, iy0)
+		g10 = p.grad(ix1, iy0)
+		g11 = p.grad(ix1, iy1)
+		g01 = p.grad(ix0, iy1)
+
+		dot00 = fx*g00.X + fy*g00.Y
+		dot10 = (fx-1)*g10.X + fy*g10.Y
+		dot11 = (fx-1)*g11.X + (fy-1)*g11.Y
+		dot01 = fx*g01.X + (fy-1)*g01.Y
+
+		u, v = fade(fx), fade(fy)
+	)
+
+	// The noise is a bilinear blend of the dot products, dot00 + k1*u + k2*v + k3*u*v
+	var (
+		k1 = dot10 - dot00
+		k2 = dot01 - dot00
+		k3 = dot11 - dot10 - dot01 + dot00
+		Z  = dot00 + k1*u + k2*v + k3*u*v
+	)
+
+	// Its gradient comes from both the blend weights and the dot products themselves
+	deriv := zmath.Vec{
+		X: fadeDeriv(fx)*(k1+k3*v) + g00.X + u*(g10.X-g00.X) + v*(g01.X-g00.X) + u*v*(g11.X-g10.X-g01.X+g00.X),
+		Y: fadeDeriv(fy)*(k2+k3*u) + g00.Y + u*(g10.Y-g00.Y) + v*(g01.Y-g00.Y) + u*v*(g11.Y-g10.Y-g01.Y+g00.Y),
 	}
 
-	// Post-analysis
-	if cfg.Normalize {
-		perlinMap.Interpolate(0, 1)
+	return Z * math.Sqrt2, deriv.Scale(math.Sqrt2)
+}
+
+// periodic returns a copy of the Perlin sampler that repeats every w by h units
+func (p *Perlin) periodic(w, h int) Sampler {
+	periodic := *p
+	periodic.PeriodX, periodic.PeriodY = w, h
+	return &periodic
+}
+
+// grad returns the gradient of the lattice point at (x, y)
+func (p *Perlin) grad(x, y int) zmath.Vec {
+	if p.improved {
+		return grad12[p.tbl.hash2(x, y)%12].Scale(improvedScale)
 	}
+	return p.tbl.grad2(x, y)
+}
 
-	return perlinMap
+// NewPerlinMap generates a new perlin noise map according to the specified configuration.
+func NewPerlinMap(cfg Config) zmath.Map {
+	cfg.Type = PerlinNoise
+	return NewMap(cfg)
 }
 
+// NewPerlinMapDeriv generates a new perlin noise map along with its exact gradient at every pixel
+func NewPerlinMapDeriv(cfg Config) (zmath.Map, zmath.MapVec) {
+	cfg.Type = PerlinNoise
+	return NewMapDeriv(cfg)
+}
+
+// improvedScale brings improved perlin noise, whose diagonal gradients are longer than 1, to the same range as
+// regular perlin noise
+const improvedScale = 0.75
+
 func interpolatePow5(i0, i1, t float64) float64 {
-	weight := 6*math.Pow(t, 5) - 15*math.Pow(t, 4) + 10*math.Pow(t, 3)
+	weight := fade(t)
 	return weight*i1 + (1.0-weight)*i0
 }
+
+// fade is the quintic 6t^5 - 15t^4 + 10t^3, which eases in and out of both 0 and 1
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+// fadeDeriv is the derivative of fade
+func fadeDeriv(t float64) float64 {
+	return 30 * t * t * (t*(t-2) + 1)
+}
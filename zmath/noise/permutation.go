@@ -0,0 +1,148 @@
+package noise
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// Sampler is anything that can produce coherent noise at arbitrary coordinates.
+// Every Sampler in this package is safe to use from multiple goroutines at once.
+type Sampler interface {
+	Eval2D(x, y float64) float64
+}
+
+// Sampler3D is a Sampler that can also produce noise in three dimensions
+type Sampler3D interface {
+	Sampler
+	Eval3D(x, y, z float64) float64
+}
+
+// Sampler4D is a Sampler3D that can also produce noise in four dimensions
+type Sampler4D interface {
+	Sampler3D
+	Eval4D(x, y, z, w float64) float64
+}
+
+// DerivSampler is a Sampler that can also produce the exact gradient (dN/dx, dN/dy) of its noise
+type DerivSampler interface {
+	Sampler
+	EvalDeriv2D(x, y float64) (float64, zmath.Vec)
+}
+
+// derivStep is how far apart evalDeriv samples Samplers that can't provide their own gradient
+const derivStep = 1e-4
+
+// evalDeriv returns the value and gradient of s at (x, y). The gradient of a Sampler that isn't a DerivSampler
+// is estimated by central differences.
+func evalDeriv(s Sampler, x, y float64) (float64, zmath.Vec) {
+	if ds, ok := s.(DerivSampler); ok {
+		return ds.EvalDeriv2D(x, y)
+	}
+	return diffDeriv(s, x, y)
+}
+
+// diffDeriv returns the value of s at (x, y), and its gradient estimated by central differences
+func diffDeriv(s Sampler, x, y float64) (float64, zmath.Vec) {
+	return s.Eval2D(x, y), zmath.Vec{
+		X: (s.Eval2D(x+derivStep, y) - s.Eval2D(x-derivStep, y)) / (2 * derivStep),
+		Y: (s.Eval2D(x, y+derivStep) - s.Eval2D(x, y-derivStep)) / (2 * derivStep),
+	}
+}
+
+// permTable is a seeded permutation of 0-255, repeated twice so that lookups never need to wrap, along with a
+// random unit gradient in 2, 3 and 4 dimensions, a random point within the unit square and a random value
+// within [-1, 1] for each entry. Hashing lattice coordinates through the permutation gives every lattice
+// point its own gradient without storing one per point.
+type permTable struct {
+	perm  [512]uint8
+	grad  [256]zmath.Vec
+	grad3 [256][3]float64
+	grad4 [256][4]float64
+	point [256]zmath.Vec
+	value [256]float64
+}
+
+// newPermTable returns a permutation table shuffled by its own random source, so the global math/rand is never
+// touched and the same seed always gives the same table
+func newPermTable(seed int64) permTable {
+	var (
+		tbl permTable
+		rng = rand.New(rand.NewSource(seed))
+	)
+
+	for i := 0; i < 256; i++ {
+		tbl.perm[i] = uint8(i)
+	}
+	for i := 255; i > 0; i-- {
+		j := rng.Intn(i + 1)
+		tbl.perm[i], tbl.perm[j] = tbl.perm[j], tbl.perm[i]
+	}
+	copy(tbl.perm[256:], tbl.perm[:256])
+
+	for i := range tbl.grad {
+		angle := rng.Float64() * 2.0 * math.Pi
+		tbl.grad[i] = zmath.V(math.Cos(angle), math.Sin(angle))
+	}
+	for i := range tbl.grad3 {
+		randomUnit(rng, tbl.grad3[i][:])
+	}
+	for i := range tbl.grad4 {
+		randomUnit(rng, tbl.grad4[i][:])
+	}
+	for i := range tbl.point {
+		tbl.point[i] = zmath.V(rng.Float64(), rng.Float64())
+	}
+	for i := range tbl.value {
+		tbl.value[i] = rng.Float64()*2 - 1
+	}
+
+	return tbl
+}
+
+// hash2 returns a number from 0-255 that is unique to the passed lattice coordinates, for every set of
+// coordinates within a 256x256 area
+func (tbl *permTable) hash2(x, y int) int {
+	return int(tbl.perm[int(tbl.perm[x&255])+(y&255)])
+}
+
+// grad2 returns the gradient of the lattice point at the passed coordinates
+func (tbl *permTable) grad2(x, y int) zmath.Vec {
+	return tbl.grad[tbl.hash2(x, y)]
+}
+
+// hash3 is the same as hash2, in three dimensions
+func (tbl *permTable) hash3(x, y, z int) int {
+	return int(tbl.perm[int(tbl.perm[int(tbl.perm[x&255])+(y&255)])+(z&255)])
+}
+
+// hash4 is the same as hash2, in four dimensions
+func (tbl *permTable) hash4(x, y, z, w int) int {
+	return int(tbl.perm[int(tbl.perm[int(tbl.perm[int(tbl.perm[x&255])+(y&255)])+(z&255)])+(w&255)])
+}
+
+// randomUnit fills vec with a random direction of length 1. Normally distributed components give every
+// direction an equal chance.
+func randomUnit(rng *rand.Rand, vec []float64) {
+	for {
+		length := 0.0
+		for i := range vec {
+			vec[i] = rng.NormFloat64()
+			length += vec[i] * vec[i]
+		}
+		if length > 1e-12 {
+			length = math.Sqrt(length)
+			for i := range vec {
+				vec[i] /= length
+			}
+			return
+		}
+	}
+}
+
+// octaveSeed returns the seed used for a single octave of a multi-octave noise map, so that octaves don't all
+// share the same lattice
+func octaveSeed(seed int64, oct int) int64 {
+	return seed + int64(oct)*7919
+}
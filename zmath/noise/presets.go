@@ -1,36 +1,60 @@
 package noise
 
 import (
-	"math"
+	"context"
 
 	"github.com/Isarcus/zarks/zmath"
 )
 
-// Riverplex is useful for lots of rivers
+// Riverplex is useful for lots of rivers. Simplex by default, but any type of noise in cfg.Type works
 func Riverplex(cfg Config) zmath.Map {
-	layer1 := NewSimplexMap(cfg).Interpolate(-1, 1)
-	theMap := NewSimplexMap(cfg).Interpolate(1, 2).GeometricMean(layer1).Interpolate(0, 1)
-
-	return theMap
+	m, _, _ := GenerateRiverplex(context.Background(), cfg)
+	return m
 }
 
-// Ridgeplex is useful for mountain ranges
-func Ridgeplex(cfg Config) zmath.Map {
-	theMap := zmath.NewMap(cfg.Dimensions, 0)
-	for oct := 0.0; int(oct) < cfg.Octaves; oct++ {
-		octInfluence := math.Pow(0.5, oct)
-		newCfg := Config{
-			Dimensions:     cfg.Dimensions,
-			Octaves:        1,
-			Normalize:      false,
-			Seed:           cfg.Seed,
-			BoxSizeInitial: cfg.BoxSizeInitial * octInfluence,
-			R:              cfg.R,
+// GenerateRiverplex is Riverplex, but also returns the seed that was used, and stops early with ctx's error if
+// ctx is cancelled. Riverplex is made of two layers of noise, which each make up half of the reported progress;
+// the second layer's seed is derived from the first's, so the one returned seed reproduces the whole map.
+func GenerateRiverplex(ctx context.Context, cfg Config) (zmath.Map, int64, error) {
+	cfg.checkDefaults()
+	var (
+		progress = cfg.Progress
+		layers   [2]zmath.Map
+	)
+	for i := range layers {
+		layerCfg := cfg
+		if i > 0 {
+			// one step of an LCG, so that the layers don't share any octaves
+			layerCfg.Seed = cfg.Seed*6364136223846793005 + 1442695040888963407
+		}
+		if progress != nil {
+			layer := i
+			layerCfg.Progress = func(done, total int) {
+				progress(layer*total+done, len(layers)*total)
+			}
+		}
+
+		m, _, err := GenerateMap(ctx, layerCfg)
+		if err != nil {
+			return nil, cfg.Seed, err
 		}
-		layer := NewSimplexMap(newCfg).Interpolate(-0.5, 1).CustomMod(math.Abs).Multiply(-1).Add(1).Multiply(octInfluence)
-		theMap.AddMap(layer) /*                     ^ this right here should be -0.5 */
+		layers[i] = m
 	}
-	theMap.Interpolate(0, 1)
 
-	return theMap
+	layers[0].Interpolate(-1, 1)
+	return layers[1].Interpolate(1, 2).GeometricMean(layers[0]).Interpolate(0, 1), cfg.Seed, nil
+}
+
+// Ridgeplex is useful for mountain ranges. It's noise (simplex by default) combined as a ridged multifractal.
+func Ridgeplex(cfg Config) zmath.Map {
+	m, _, _ := GenerateRidgeplex(context.Background(), cfg)
+	return m
+}
+
+// GenerateRidgeplex is Ridgeplex, but also returns the seed that was used, and stops early with ctx's error if
+// ctx is cancelled
+func GenerateRidgeplex(ctx context.Context, cfg Config) (zmath.Map, int64, error) {
+	cfg.Fractal = RidgedMulti
+	cfg.Normalize = true
+	return GenerateMap(ctx, cfg)
 }
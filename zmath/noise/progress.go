@@ -0,0 +1,46 @@
+package noise
+
+import (
+	"context"
+	"sync"
+)
+
+// tracker reports a generator's progress through a Config's Progress callback, and lets it know when its
+// context has been cancelled. It's safe to use from multiple goroutines at once; Progress is never called by
+// more than one at a time.
+type tracker struct {
+	ctx      context.Context
+	progress func(done, total int)
+
+	mu          sync.Mutex
+	done, total int
+}
+
+func newTracker(ctx context.Context, progress func(done, total int), total int) *tracker {
+	return &tracker{
+		ctx:      ctx,
+		progress: progress,
+		total:    total,
+	}
+}
+
+// cancelled returns whether the generator should stop early
+func (tr *tracker) cancelled() bool {
+	return tr.ctx.Err() != nil
+}
+
+// err returns the context's error, if it was cancelled
+func (tr *tracker) err() error {
+	return tr.ctx.Err()
+}
+
+// step reports that one more unit of work is done
+func (tr *tracker) step() {
+	if tr.progress == nil {
+		return
+	}
+	tr.mu.Lock()
+	tr.done++
+	tr.progress(tr.done, tr.total)
+	tr.mu.Unlock()
+}
@@ -1,10 +1,7 @@
 package noise
 
 import (
-	"fmt"
 	"math"
-	"math/rand"
-	"strconv"
 
 	"github.com/Isarcus/zarks/zmath"
 )
@@ -13,79 +10,217 @@ import (
 var (
 	F2D float64 = (math.Sqrt(3) - 1.0) / 2.0
 	G2D float64 = (1.0 - 1.0/math.Sqrt(3)) / 2.0
+	F3D float64 = 1.0 / 3.0
+	G3D float64 = 1.0 / 6.0
+	F4D float64 = (math.Sqrt(5) - 1.0) / 4.0
+	G4D float64 = (5.0 - math.Sqrt(5)) / 20.0
 )
 
-// NewSimplexMap generates a new simplex noise map according to the specified configuration.
-func NewSimplexMap(cfg Config) zmath.Map {
-	cfg.checkDefaults()
-	r2 := cfg.R * cfg.R
-	simplexMap := zmath.NewMap(cfg.Dimensions, 0)
-
-	for oct := 0.0; oct < float64(cfg.Octaves); oct++ {
-		// create a new hashmap for angles
-		vecMap := make(map[string]float64)
-		octInfluence := math.Pow(0.5, oct)
-
-		// dive in
-		for x := 0; x < cfg.Dimensions.X; x++ {
-			for y := 0; y < cfg.Dimensions.Y; y++ {
-				ipt := zmath.V(
-					float64(x)/(cfg.BoxSizeInitial*octInfluence),
-					float64(y)/(cfg.BoxSizeInitial*octInfluence),
-				)
-
-				// skew input coordinates
-				skewed := skew(ipt)
-
-				// find internal coordinates of simplex
-				var corners [3]zmath.Vec
-				var vectors [3]zmath.Vec
-				corners[0] = zmath.V(math.Floor(skewed.X), math.Floor(skewed.Y))
-				internal := zmath.V(skewed.X-corners[0].X, skewed.Y-corners[0].Y)
-				if internal.X > internal.Y {
-					corners[1] = corners[0].Add(zmath.V(1, 0))
-				} else {
-					corners[1] = corners[0].Add(zmath.V(0, 1))
-				}
-				corners[2] = corners[0].Add(zmath.V(1, 1))
-
-				// get vectors of the three corners
-				for i := 0; i < 3; i++ {
-					key := getKey(corners[i].VI())
-					if angle, ok := vecMap[key]; ok { // if random angle already determined, use it
-						vectors[i] = zmath.V(math.Cos(angle), math.Sin(angle))
-					} else { // otherwise, generate one for this and future use
-						angle = rand.Float64() * 2.0 * math.Pi
-						vectors[i] = zmath.V(math.Cos(angle), math.Sin(angle))
-						vecMap[key] = angle
-					}
-				}
-
-				// determine distance to each unskewed corner
-				var disp [3]zmath.Vec
-				var distance [3]float64
-				for i := range distance {
-					disp[i] = ipt.Subtract(unskew(corners[i]))
-					distance[i] = math.Sqrt(disp[i].X*disp[i].X + disp[i].Y*disp[i].Y)
-				}
-
-				var Z float64
-				for i, dist := range distance {
-					influence := math.Pow(math.Max(0, r2-dist*dist), 4) // HEY, REDUCE POWER FOR COOL EFFECT!!!
-					Z += influence * disp[i].Dot(vectors[i])
-				}
-
-				simplexMap[x][y] += Z * octInfluence
+// Simplex is a seeded simplex noise sampler. It doesn't allocate when sampled and is safe to use from multiple
+// goroutines at once.
+type Simplex struct {
+	R float64 // size of radius of influence for each corner's vector. Eval3D and Eval4D always use sqrt(0.5)
+
+	tbl permTable
+}
+
+// NewSimplex returns a new Simplex sampler. The same seed always produces the same noise.
+func NewSimplex(seed int64) *Simplex {
+	return &Simplex{
+		R:   DefaultConfig.R,
+		tbl: newPermTable(seed),
+	}
+}
+
+// Eval2D returns the value of the noise at (x, y), where a distance of 1 is the size of one simplex.
+// With the default R, values fall roughly within [-1, 1].
+func (s *Simplex) Eval2D(x, y float64) float64 {
+	val, _ := s.EvalDeriv2D(x, y)
+	return val
+}
+
+// EvalDeriv2D returns the value of the noise at (x, y), along with its exact gradient there
+func (s *Simplex) EvalDeriv2D(x, y float64) (float64, zmath.Vec) {
+	// skew input coordinates, then find internal coordinates of simplex
+	var (
+		skewed  = (x + y) * F2D
+		cornerX = math.Floor(x + skewed)
+		cornerY = math.Floor(y + skewed)
+		ix, iy  = int(cornerX), int(cornerY)
+
+		// which of the two triangles in the skewed square are we in?
+		midX, midY = 0, 1
+	)
+	if x+skewed-cornerX > y+skewed-cornerY {
+		midX, midY = 1, 0
+	}
+
+	var (
+		r2      = s.R * s.R
+		Z       float64
+		deriv   zmath.Vec
+		offsets = [3][2]int{{0, 0}, {midX, midY}, {1, 1}}
+	)
+	for _, off := range offsets {
+		// displacement from the unskewed corner
+		cx, cy := cornerX+float64(off[0]), cornerY+float64(off[1])
+		unskewed := (cx + cy) * G2D
+		dx, dy := x-(cx-unskewed), y-(cy-unskewed)
+
+		t := r2 - dx*dx - dy*dy
+		if t <= 0 {
+			continue
+		}
+		var (
+			t3   = t * t * t
+			grad = s.tbl.grad2(ix+off[0], iy+off[1])
+			dot  = dx*grad.X + dy*grad.Y
+		)
+		Z += t3 * t * dot
+
+		// d/dx of t^4 * dot = t^4 * grad.X - 8 * t^3 * dx * dot
+		deriv.X += t3 * (t*grad.X - 8*dx*dot)
+		deriv.Y += t3 * (t*grad.Y - 8*dy*dot)
+	}
+
+	scale := simplexScale(s.R)
+	return Z * scale, deriv.Scale(scale)
+}
+
+// simplexScale returns what the raw sum of corner influences must be multiplied by to fall roughly within [-1, 1]
+func simplexScale(r float64) float64 {
+	return 1.0 / (simplexPeak * math.Pow(r/DefaultConfig.R, 9))
+}
+
+// Eval3D returns the value of the noise at (x, y, z), where a distance of 1 is the size of one simplex.
+// Values fall roughly within [-1, 1].
+func (s *Simplex) Eval3D(x, y, z float64) float64 {
+	var (
+		pt     = [3]float64{x, y, z}
+		skewed = (x + y + z) * F3D
+		corner [3]float64
+		lat    [3]int
+		rel    [3]float64 // position relative to the first corner
+	)
+	for i := range pt {
+		corner[i] = math.Floor(pt[i] + skewed)
+		lat[i] = int(corner[i])
+	}
+	unskewed := (corner[0] + corner[1] + corner[2]) * G3D
+	for i := range pt {
+		rel[i] = pt[i] - (corner[i] - unskewed)
+	}
+
+	// Corner n of the simplex is offset by 1 along each of the n axes that rel is largest along
+	rank := simplexRanks(rel[:])
+
+	Z := 0.0
+	for n := 0; n <= 3; n++ {
+		var (
+			d         [3]float64
+			off       [3]int
+			influence = 0.5
+		)
+		for i := range d {
+			if rank[i] >= 3-n {
+				off[i] = 1
+			}
+			d[i] = rel[i] - float64(off[i]) + float64(n)*G3D
+			influence -= d[i] * d[i]
+		}
+		if influence <= 0 {
+			continue
+		}
+		influence *= influence
+		influence *= influence
+
+		grad := &s.tbl.grad3[s.tbl.hash3(lat[0]+off[0], lat[1]+off[1], lat[2]+off[2])]
+		Z += influence * (d[0]*grad[0] + d[1]*grad[1] + d[2]*grad[2])
+	}
+
+	return Z / simplexPeak3D
+}
+
+// Eval4D returns the value of the noise at (x, y, z, w), where a distance of 1 is the size of one simplex.
+// Values fall roughly within [-1, 1].
+func (s *Simplex) Eval4D(x, y, z, w float64) float64 {
+	var (
+		pt     = [4]float64{x, y, z, w}
+		skewed = (x + y + z + w) * F4D
+		corner [4]float64
+		lat    [4]int
+		rel    [4]float64
+	)
+	for i := range pt {
+		corner[i] = math.Floor(pt[i] + skewed)
+		lat[i] = int(corner[i])
+	}
+	unskewed := (corner[0] + corner[1] + corner[2] + corner[3]) * G4D
+	for i := range pt {
+		rel[i] = pt[i] - (corner[i] - unskewed)
+	}
+
+	rank := simplexRanks(rel[:])
+
+	Z := 0.0
+	for n := 0; n <= 4; n++ {
+		var (
+			d         [4]float64
+			off       [4]int
+			influence = 0.5
+		)
+		for i := range d {
+			if rank[i] >= 4-n {
+				off[i] = 1
 			}
+			d[i] = rel[i] - float64(off[i]) + float64(n)*G4D
+			influence -= d[i] * d[i]
 		}
-		fmt.Println("Octave ", oct+1, " finished.")
+		if influence <= 0 {
+			continue
+		}
+		influence *= influence
+		influence *= influence
+
+		grad := &s.tbl.grad4[s.tbl.hash4(lat[0]+off[0], lat[1]+off[1], lat[2]+off[2], lat[3]+off[3])]
+		Z += influence * (d[0]*grad[0] + d[1]*grad[1] + d[2]*grad[2] + d[3]*grad[3])
 	}
 
-	if cfg.Normalize {
-		simplexMap.Interpolate(0, 1)
+	return Z / simplexPeak4D
+}
+
+// simplexRanks returns, for each axis, how many other axes rel is smaller along. Ties go to the earlier axis,
+// so every axis gets a different rank.
+func simplexRanks(rel []float64) (rank [4]int) {
+	for i := range rel {
+		for j := i + 1; j < len(rel); j++ {
+			if rel[i] >= rel[j] {
+				rank[i]++
+			} else {
+				rank[j]++
+			}
+		}
 	}
+	return
+}
+
+// About the largest raw values simplex noise reaches in each dimension, with the default radius of influence
+const (
+	simplexPeak   = 0.0022
+	simplexPeak3D = 0.0095
+	simplexPeak4D = 0.0095
+)
 
-	return simplexMap
+// NewSimplexMap generates a new simplex noise map according to the specified configuration.
+func NewSimplexMap(cfg Config) zmath.Map {
+	cfg.Type = SimplexNoise
+	return NewMap(cfg)
+}
+
+// NewSimplexMapDeriv generates a new simplex noise map along with its exact gradient at every pixel
+func NewSimplexMapDeriv(cfg Config) (zmath.Map, zmath.MapVec) {
+	cfg.Type = SimplexNoise
+	return NewMapDeriv(cfg)
 }
 
 func skew(vec zmath.Vec) zmath.Vec {
@@ -101,8 +236,3 @@ func unskew(vec zmath.Vec) zmath.Vec {
 		Y: vec.Y - (vec.X+vec.Y)*G2D,
 	}
 }
-
-// getKey returns a unique string key for the given integer coordinates
-func getKey(vec zmath.VecInt) string {
-	return strconv.Itoa(vec.X) + "." + strconv.Itoa(vec.Y)
-}
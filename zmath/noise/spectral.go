@@ -0,0 +1,57 @@
+package noise
+
+import (
+	"context"
+	"math"
+	"math/rand"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// NewSpectralMap generates a map of 1/f^Beta noise by spectral synthesis: every frequency gets a random phase and
+// a random strength that falls off with frequency, and the whole spectrum is run through an inverse FFT. Beta 2,
+// as in DefaultConfig, looks like rolling terrain; higher is smoother and lower is rougher, with 0 for white
+// noise. Frequencies with wavelengths longer than BoxSizeInitial are all weighted the same, which limits how
+// large features get.
+// The map always tiles seamlessly; Octaves, Lacunarity, Persistence, Fractal and Type are ignored.
+func NewSpectralMap(cfg Config) zmath.Map {
+	m, _, _ := GenerateSpectralMap(context.Background(), cfg)
+	return m
+}
+
+// GenerateSpectralMap is NewSpectralMap, but also returns the seed that was used, and stops early with ctx's
+// error if ctx is cancelled
+func GenerateSpectralMap(ctx context.Context, cfg Config) (zmath.Map, int64, error) {
+	cfg.checkDefaults()
+	var (
+		rng     = rand.New(rand.NewSource(cfg.Seed))
+		spec    = zmath.NewSpectrum(cfg.Dimensions)
+		minFreq = 1 / cfg.BoxSizeInitial
+	)
+	for x := range spec {
+		if ctx.Err() != nil {
+			return nil, cfg.Seed, ctx.Err()
+		}
+		for y := range spec[x] {
+			if x == 0 && y == 0 {
+				continue // the mean
+			}
+			var (
+				f      = spec.FrequencyAt(zmath.VI(x, y))
+				freq   = math.Max(math.Hypot(f.X, f.Y), minFreq)
+				amp    = math.Pow(freq, -cfg.Beta/2)
+				re, im = rng.NormFloat64(), rng.NormFloat64()
+			)
+			spec[x][y] = complex(re*amp, im*amp)
+		}
+	}
+
+	m := spec.Inverse()
+	if ctx.Err() != nil {
+		return nil, cfg.Seed, ctx.Err()
+	}
+	if cfg.Normalize {
+		m.Interpolate(0, 1)
+	}
+	return m, cfg.Seed, nil
+}
@@ -0,0 +1,96 @@
+package noise
+
+import (
+	"math"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// periodic is implemented by Samplers that can repeat themselves exactly by wrapping their lattice
+type periodic interface {
+	Sampler
+	periodic(w, h int) Sampler
+}
+
+// Tile returns a Sampler that repeats every w units along x and h units along y. Samplers whose lattice can
+// simply wrap around (like Perlin) repeat exactly, and look the same as the original. Any other Sampler is made
+// to repeat by blending four samples together, which is seamless but slightly lower in contrast.
+func Tile(s Sampler, w, h int) Sampler {
+	if p, ok := s.(periodic); ok {
+		return p.periodic(w, h)
+	}
+	return tiled{
+		Sampler: s,
+		w:       float64(w),
+		h:       float64(h),
+	}
+}
+
+// tiled makes any Sampler repeat by blending samples from each of the four corners of the tile
+type tiled struct {
+	Sampler
+	w, h float64
+}
+
+func (t tiled) Eval2D(x, y float64) float64 {
+	x = wrapFloat(x, t.w)
+	y = wrapFloat(y, t.h)
+
+	return (t.Sampler.Eval2D(x, y)*(t.w-x)*(t.h-y) +
+		t.Sampler.Eval2D(x-t.w, y)*x*(t.h-y) +
+		t.Sampler.Eval2D(x, y-t.h)*(t.w-x)*y +
+		t.Sampler.Eval2D(x-t.w, y-t.h)*x*y) / (t.w * t.h)
+}
+
+func (t tiled) EvalDeriv2D(x, y float64) (float64, zmath.Vec) {
+	x = wrapFloat(x, t.w)
+	y = wrapFloat(y, t.h)
+
+	var (
+		v00, d00 = evalDeriv(t.Sampler, x, y)
+		v10, d10 = evalDeriv(t.Sampler, x-t.w, y)
+		v01, d01 = evalDeriv(t.Sampler, x, y-t.h)
+		v11, d11 = evalDeriv(t.Sampler, x-t.w, y-t.h)
+		area     = t.w * t.h
+	)
+
+	// product rule on each of the four blended samples
+	val := (v00*(t.w-x)*(t.h-y) + v10*x*(t.h-y) + v01*(t.w-x)*y + v11*x*y) / area
+	return val, zmath.Vec{
+		X: (d00.X*(t.w-x)*(t.h-y) + d10.X*x*(t.h-y) + d01.X*(t.w-x)*y + d11.X*x*y +
+			(v10-v00)*(t.h-y) + (v11-v01)*y) / area,
+		Y: (d00.Y*(t.w-x)*(t.h-y) + d10.Y*x*(t.h-y) + d01.Y*(t.w-x)*y + d11.Y*x*y +
+			(v01-v00)*(t.w-x) + (v11-v10)*x) / area,
+	}
+}
+
+// wrapBoxes returns the box size closest to the one passed that fits a whole number of times across each of
+// the passed dimensions, along with how many boxes fit.
+func wrapBoxes(dim zmath.VecInt, boxSize float64) (zmath.Vec, zmath.VecInt) {
+	boxes := zmath.VI(
+		zmath.MaxInt(1, int(math.Round(float64(dim.X)/boxSize))),
+		zmath.MaxInt(1, int(math.Round(float64(dim.Y)/boxSize))),
+	)
+	return zmath.V(float64(dim.X)/float64(boxes.X), float64(dim.Y)/float64(boxes.Y)), boxes
+}
+
+// wrapInt returns i modulo period, always in [0, period). Periods of 0 or less don't wrap at all.
+func wrapInt(i, period int) int {
+	if period <= 0 {
+		return i
+	}
+	i %= period
+	if i < 0 {
+		i += period
+	}
+	return i
+}
+
+// wrapFloat returns f modulo period, always in [0, period)
+func wrapFloat(f, period float64) float64 {
+	f = math.Mod(f, period)
+	if f < 0 {
+		f += period
+	}
+	return f
+}
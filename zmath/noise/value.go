@@ -0,0 +1,67 @@
+package noise
+
+import (
+	"math"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// Value is a seeded value noise sampler. Every lattice point gets a random value, and the noise smoothly blends
+// between them. It's blurrier and blockier than gradient noise like Perlin, but very cheap. It doesn't allocate
+// when sampled and is safe to use from multiple goroutines at once.
+type Value struct {
+	// If nonzero, the noise repeats every PeriodX units along x and every PeriodY units along y
+	PeriodX, PeriodY int
+
+	tbl permTable
+}
+
+// NewValue returns a new Value sampler. The same seed always produces the same noise.
+func NewValue(seed int64) *Value {
+	return &Value{
+		tbl: newPermTable(seed),
+	}
+}
+
+// Eval2D returns the value of the noise at (x, y), where a distance of 1 is the size of one box.
+// Values fall within [-1, 1].
+func (v *Value) Eval2D(x, y float64) float64 {
+	val, _ := v.EvalDeriv2D(x, y)
+	return val
+}
+
+// EvalDeriv2D returns the value of the noise at (x, y), along with its exact gradient there
+func (v *Value) EvalDeriv2D(x, y float64) (float64, zmath.Vec) {
+	var (
+		boxX, boxY = math.Floor(x), math.Floor(y)
+		ix, iy     = int(boxX), int(boxY)
+
+		ix0, ix1 = wrapInt(ix, v.PeriodX), wrapInt(ix+1, v.PeriodX)
+		iy0, iy1 = wrapInt(iy, v.PeriodY), wrapInt(iy+1, v.PeriodY)
+	)
+
+	var (
+		v00 = v.tbl.value[v.tbl.hash2(ix0, iy0)]
+		v10 = v.tbl.value[v.tbl.hash2(ix1, iy0)]
+		v01 = v.tbl.value[v.tbl.hash2(ix0, iy1)]
+		v11 = v.tbl.value[v.tbl.hash2(ix1, iy1)]
+
+		fx, fy = x - boxX, y - boxY
+		u, w   = fade(fx), fade(fy)
+		k1     = v10 - v00
+		k2     = v01 - v00
+		k3     = v11 - v10 - v01 + v00
+	)
+
+	return v00 + k1*u + k2*w + k3*u*w, zmath.Vec{
+		X: fadeDeriv(fx) * (k1 + k3*w),
+		Y: fadeDeriv(fy) * (k2 + k3*u),
+	}
+}
+
+// periodic returns a copy of the Value sampler that repeats every w by h units
+func (v *Value) periodic(w, h int) Sampler {
+	periodic := *v
+	periodic.PeriodX, periodic.PeriodY = w, h
+	return &periodic
+}
@@ -0,0 +1,106 @@
+package noise
+
+import (
+	"context"
+	"math"
+
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// NewSimplexVolume generates depth slices of 3D simplex noise, one pixel apart. The slices can be stacked up as
+// volumetric data, or played one after another to animate 2D noise over time. If cfg.Normalize is set, every
+// slice is normalized together so that their values stay comparable.
+func NewSimplexVolume(cfg Config, depth int) []zmath.Map {
+	cfg.checkDefaults()
+	normalize := cfg.Normalize
+	cfg.Normalize = false
+
+	var (
+		slices = make([]zmath.Map, depth)
+		tr     = newTracker(context.Background(), cfg.Progress, depth*cfg.Dimensions.X)
+	)
+	for z := range slices {
+		slices[z] = sumOctaves(tr, cfg, 0, nil, func(seed int64, boxSize float64) Sampler {
+			return slice3D{
+				s: NewSimplex(seed),
+				z: float64(z) / boxSize,
+			}
+		})
+	}
+
+	if normalize {
+		normalizeAll(slices)
+	}
+	return slices
+}
+
+// NewSimplexLoop generates frames of 2D noise that change smoothly over time and loop back around to the first
+// frame seamlessly. Each frame is a slice of 4D simplex noise, taken along a circle in the third and fourth
+// dimensions whose circumference is one pixel per frame. If cfg.Normalize is set, every frame is normalized
+// together so that the animation doesn't flicker.
+func NewSimplexLoop(cfg Config, frames int) []zmath.Map {
+	cfg.checkDefaults()
+	normalize := cfg.Normalize
+	cfg.Normalize = false
+
+	var (
+		loop   = make([]zmath.Map, frames)
+		radius = float64(frames) / (2.0 * math.Pi)
+		tr     = newTracker(context.Background(), cfg.Progress, frames*cfg.Dimensions.X)
+	)
+	for f := range loop {
+		angle := 2.0 * math.Pi * float64(f) / float64(frames)
+		loop[f] = sumOctaves(tr, cfg, 0, nil, func(seed int64, boxSize float64) Sampler {
+			return slice4D{
+				s: NewSimplex(seed),
+				z: radius * math.Cos(angle) / boxSize,
+				w: radius * math.Sin(angle) / boxSize,
+			}
+		})
+	}
+
+	if normalize {
+		normalizeAll(loop)
+	}
+	return loop
+}
+
+// slice3D samples a plane of 3D noise at a fixed depth
+type slice3D struct {
+	s Sampler3D
+	z float64
+}
+
+func (sl slice3D) Eval2D(x, y float64) float64 {
+	return sl.s.Eval3D(x, y, sl.z)
+}
+
+// slice4D samples a plane of 4D noise at a fixed position along the third and fourth axes
+type slice4D struct {
+	s    Sampler4D
+	z, w float64
+}
+
+func (sl slice4D) Eval2D(x, y float64) float64 {
+	return sl.s.Eval4D(x, y, sl.z, sl.w)
+}
+
+// normalizeAll interpolates every Map to [0, 1], using the min and max across all of them. If every point of
+// every Map is the same, they're all set to 0, just like Map.Interpolate.
+func normalizeAll(maps []zmath.Map) {
+	if len(maps) == 0 {
+		return
+	}
+	min, max := maps[0].GetMinMax()
+	for _, m := range maps[1:] {
+		mMin, mMax := m.GetMinMax()
+		min, max = math.Min(min, mMin), math.Max(max, mMax)
+	}
+	for _, m := range maps {
+		if max == min {
+			m.Clear(0)
+		} else {
+			m.Subtract(min).Multiply(1.0 / (max - min))
+		}
+	}
+}
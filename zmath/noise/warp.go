@@ -0,0 +1,87 @@
+package noise
+
+import "github.com/Isarcus/zarks/zmath"
+
+// Warp is a Sampler that displaces its input coordinates by the values of two other Samplers before sampling
+// its Base, which produces swirling, folded shapes. With more than one iteration, the displacement is looked up
+// again at the displaced coordinates each time, so that the folds fold over themselves.
+type Warp struct {
+	Base       Sampler
+	X, Y       Sampler // displacement along each axis
+	Strength   float64 // how far to displace by, for displacement values of 1
+	Iterations int
+}
+
+// NewWarp returns a Warp that samples base at coordinates displaced once by x and y, scaled by strength.
+// Any Samplers may be used, but displacements should be centered on 0 (like Simplex and Perlin), or the
+// warped noise will drift to one side.
+func NewWarp(base, x, y Sampler, strength float64) *Warp {
+	return &Warp{
+		Base:       base,
+		X:          x,
+		Y:          y,
+		Strength:   strength,
+		Iterations: 1,
+	}
+}
+
+// Eval2D returns the value of the Base noise at (x, y) after warping
+func (w *Warp) Eval2D(x, y float64) float64 {
+	warpedX, warpedY := x, y
+	for i := 0; i < w.Iterations; i++ {
+		warpedX, warpedY = x+w.Strength*w.X.Eval2D(warpedX, warpedY), y+w.Strength*w.Y.Eval2D(warpedX, warpedY)
+	}
+	return w.Base.Eval2D(warpedX, warpedY)
+}
+
+// EvalDeriv2D returns the value of the Base noise at (x, y) after warping, along with its gradient with respect
+// to the unwarped coordinates
+func (w *Warp) EvalDeriv2D(x, y float64) (float64, zmath.Vec) {
+	var (
+		warpedX, warpedY = x, y
+
+		// Jacobian of the warped coordinates with respect to (x, y), starting from the identity
+		jxx, jxy = 1.0, 0.0
+		jyx, jyy = 0.0, 1.0
+	)
+	for i := 0; i < w.Iterations; i++ {
+		var (
+			dispX, gradX = evalDeriv(w.X, warpedX, warpedY)
+			dispY, gradY = evalDeriv(w.Y, warpedX, warpedY)
+			s            = w.Strength
+		)
+		jxx, jxy, jyx, jyy =
+			1+s*(gradX.X*jxx+gradX.Y*jyx), s*(gradX.X*jxy+gradX.Y*jyy),
+			s*(gradY.X*jxx+gradY.Y*jyx), 1+s*(gradY.X*jxy+gradY.Y*jyy)
+		warpedX, warpedY = x+s*dispX, y+s*dispY
+	}
+
+	val, grad := evalDeriv(w.Base, warpedX, warpedY)
+	return val, zmath.Vec{
+		X: grad.X*jxx + grad.Y*jyx,
+		Y: grad.X*jxy + grad.Y*jyy,
+	}
+}
+
+// Warp seeds are kept far from those of a map's own octaves
+const (
+	warpSeedX int64 = 104729
+	warpSeedY int64 = 224737
+)
+
+// warp warps a map's noise by two fBm simplex fractals with the same octaves as the map, so that the warp
+// still lines up across chunks and repeats along with the map when cfg.Wrap is set
+func (cfg Config) warp(s Sampler, boxSize float64) Sampler {
+	simplex := func(seed int64, _ float64) Sampler {
+		return NewSimplex(seed)
+	}
+
+	w := NewWarp(
+		s,
+		cfg.fractal(FBM, cfg.Seed+warpSeedX, boxSize, simplex),
+		cfg.fractal(FBM, cfg.Seed+warpSeedY, boxSize, simplex),
+		cfg.WarpStrength,
+	)
+	w.Iterations = cfg.WarpIterations
+	return w
+}
@@ -1,40 +1,166 @@
 package noise
 
-import "github.com/Isarcus/zarks/zmath"
+import (
+	"math"
 
-// NewWorleyMap returns a new Worley Noise map (WORK IN PROGRESS)
-func NewWorleyMap(cfg Config) zmath.Map {
-	cfg.checkDefaults()
-	noiseMap := zmath.NewMap(cfg.Dimensions, 0)
+	"github.com/Isarcus/zarks/zmath"
+)
+
+// Metric is a way of measuring the distance between two points
+type Metric int
+
+// Metrics
+const (
+	Euclidean Metric = iota // straight-line distance
+	Manhattan               // sum of the distances along each axis
+	Chebyshev               // largest of the distances along each axis
+)
+
+// distance returns the length of (dx, dy) as measured by the Metric
+func (m Metric) distance(dx, dy float64) float64 {
+	switch m {
+	case Manhattan:
+		return math.Abs(dx) + math.Abs(dy)
+	case Chebyshev:
+		return math.Max(math.Abs(dx), math.Abs(dy))
+	default:
+		return math.Sqrt(dx*dx + dy*dy)
+	}
+}
+
+// Feature is what Worley noise outputs at each point
+type Feature int
+
+// Features
+const (
+	F1        Feature = iota // distance to the nearest point
+	F2                       // distance to the second nearest point
+	F2MinusF1                // difference between the above, which is 0 along cell borders
+	FN                       // distance to the Nth nearest point, up to the 8th
+	CellID                   // a value in [0, 1] unique to the nearest point's cell, for flat-colored cells
+)
 
-	var neighbors = make([]zmath.VecInt, 21)
+// maxWorleyN is the furthest nearest point that Worley noise can measure the distance to
+const maxWorleyN = 8
+
+// worleyNeighbors holds every cell within 2 cells of the center, apart from the four corners. A point that
+// jitters within its own cell can't be any closer than those in this area, unless it's the Nth nearest for
+// fairly large N.
+var worleyNeighbors = func() (neighbors [21][2]int) {
 	idx := 0
 	for x := -2; x <= 2; x++ {
 		for y := -2; y <= 2; y++ {
-			if x*y != 4 && x*y != -4 { //&& (x != 0 || y != 0) {
-				neighbors[idx] = zmath.VI(x, y)
+			if x*y != 4 && x*y != -4 {
+				neighbors[idx] = [2]int{x, y}
 				idx++
 			}
 		}
 	}
+	return
+}()
 
-	//ptMap := make(map[string]zmath.Vec)
+// Worley is a seeded Worley (cellular) noise sampler. Every cell of the lattice has one point in it, and the
+// noise depends on the distance to the nearest of those points. It doesn't allocate when sampled and is safe
+// to use from multiple goroutines at once.
+type Worley struct {
+	Metric  Metric
+	Feature Feature
+	N       int     // FN only
+	Jitter  float64 // how far points may stray from the center of their cell, from 0 (a grid) to 1
 
-	for oct := 0; oct < cfg.Octaves; oct++ {
-		//octInfluence := math.Pow(0.5, float64(oct))
-		//boxSize := cfg.BoxSizeInitial * octInfluence
+	// If nonzero, the noise repeats every PeriodX units along x and every PeriodY units along y
+	PeriodX, PeriodY int
 
-		for x := 0; x < cfg.Dimensions.X; x++ {
-			for y := 0; y < cfg.Dimensions.Y; y++ {
-				/*box := zmath.VecInt{
-					X: int(float64(x) / boxSize),
-					Y: int(float64(y) / boxSize),
-				}
-				//pos := zmath*/
+	tbl permTable
+}
 
-			}
+// NewWorley returns a new Worley sampler that outputs the Euclidean distance to the nearest point.
+// The same seed always produces the same noise.
+func NewWorley(seed int64) *Worley {
+	return &Worley{
+		Metric:  Euclidean,
+		Feature: F1,
+		N:       DefaultConfig.N,
+		Jitter:  1,
+		tbl:     newPermTable(seed),
+	}
+}
+
+// Eval2D returns the value of the noise at (x, y), where a distance of 1 is the size of one cell.
+// Distances are usually within [0, 1.5]; cell IDs are within [0, 1].
+func (w *Worley) Eval2D(x, y float64) float64 {
+	var (
+		ix, iy = int(math.Floor(x)), int(math.Floor(y))
+		n      = w.nearestNeeded()
+
+		nearest   [maxWorleyN]float64 // distances to the n nearest points, in order
+		nearestID int
+	)
+	for i := range nearest {
+		nearest[i] = math.Inf(1)
+	}
+
+	for _, off := range worleyNeighbors {
+		var (
+			cellX, cellY = ix + off[0], iy + off[1]
+			hash         = w.tbl.hash2(wrapInt(cellX, w.PeriodX), wrapInt(cellY, w.PeriodY))
+			pt           = w.tbl.point[hash]
+			dist         = w.Metric.distance(
+				float64(cellX)+0.5+w.Jitter*(pt.X-0.5)-x,
+				float64(cellY)+0.5+w.Jitter*(pt.Y-0.5)-y,
+			)
+		)
+		if dist >= nearest[n-1] {
+			continue
+		}
+
+		// insert the distance in order
+		i := n - 1
+		for ; i > 0 && nearest[i-1] > dist; i-- {
+			nearest[i] = nearest[i-1]
 		}
+		nearest[i] = dist
+		if i == 0 {
+			nearestID = hash
+		}
+	}
+
+	switch w.Feature {
+	case F2:
+		return nearest[1]
+	case F2MinusF1:
+		return nearest[1] - nearest[0]
+	case FN:
+		return nearest[n-1]
+	case CellID:
+		return float64(nearestID) / 255.0
+	default:
+		return nearest[0]
 	}
+}
+
+// nearestNeeded returns how many of the nearest points the Worley's Feature depends on
+func (w *Worley) nearestNeeded() int {
+	switch w.Feature {
+	case F2, F2MinusF1:
+		return 2
+	case FN:
+		return zmath.MinInt(zmath.MaxInt(w.N, 1), maxWorleyN)
+	default:
+		return 1
+	}
+}
 
-	return noiseMap
+// periodic returns a copy of the Worley sampler that repeats every w by h units
+func (w *Worley) periodic(width, height int) Sampler {
+	periodic := *w
+	periodic.PeriodX, periodic.PeriodY = width, height
+	return &periodic
+}
+
+// NewWorleyMap generates a new Worley noise map according to the specified configuration, using
+// cfg.Metric and cfg.Feature. Each octave's cells are half the size of the last.
+func NewWorleyMap(cfg Config) zmath.Map {
+	cfg.Type = WorleyNoise
+	return NewMap(cfg)
 }
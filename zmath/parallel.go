@@ -0,0 +1,152 @@
+package zmath
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Map-wide operations split their columns across a pool of goroutines. Every column is always computed the
+// same way no matter which goroutine ends up computing it, and anything that combines columns (like GetSum)
+// does so in column order afterwards, so results never depend on the number of goroutines used.
+
+// parallelMinCells is the size below which maps are processed on the calling goroutine, since starting
+// goroutines would take longer than the work itself
+const parallelMinCells = 1 << 14
+
+var concurrency int32
+
+// SetConcurrency sets the maximum number of goroutines that Map and FlatMap operations may use.
+// Passing 1 makes every operation run on the calling goroutine; passing 0 (the default) uses GOMAXPROCS.
+func SetConcurrency(n int) {
+	atomic.StoreInt32(&concurrency, int32(MaxInt(n, 0)))
+}
+
+// Concurrency returns the maximum number of goroutines that Map and FlatMap operations may use
+func Concurrency() int {
+	if n := atomic.LoadInt32(&concurrency); n > 0 {
+		return int(n)
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// ParallelFor calls fn once for every i in [0, n), spreading the calls across up to Concurrency() goroutines.
+// fn must be safe to call concurrently for different values of i. ParallelFor returns once every call has
+// returned; if any call panics, ParallelFor panics with the same value.
+func ParallelFor(n int, fn func(i int)) {
+	ParallelForN(n, Concurrency(), fn)
+}
+
+// ParallelForN is the same as ParallelFor, but with an explicit maximum number of goroutines
+func ParallelForN(n, workers int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	var (
+		next     int64 = -1
+		wg       sync.WaitGroup
+		panicked sync.Once
+		recovery interface{}
+	)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					panicked.Do(func() { recovery = r })
+					atomic.StoreInt64(&next, int64(n)) // stop handing out work
+				}
+			}()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if recovery != nil {
+		panic(recovery)
+	}
+}
+
+// workersFor returns how many goroutines should be used to process the given number of cells
+func workersFor(cells int) int {
+	if cells < parallelMinCells {
+		return 1
+	}
+	return Concurrency()
+}
+
+// columns calls fn on every column of the Map, in parallel if the Map is big enough
+func (m Map) columns(fn func(x int, col []float64)) {
+	if len(m) == 0 {
+		return
+	}
+	ParallelForN(len(m), workersFor(len(m)*len(m[0])), func(x int) {
+		fn(x, m[x])
+	})
+}
+
+// columns calls fn on every column of the FlatMap, in parallel if the FlatMap is big enough
+func (f FlatMap) columns(fn func(x int, col []float64)) {
+	ParallelForN(f.Width, workersFor(f.Width*f.Height), func(x int) {
+		fn(x, f.Column(x))
+	})
+}
+
+// sumColumns adds up each column separately, then adds up the column sums in order
+func sumColumns(width int, each func(func(x int, col []float64))) float64 {
+	sums := make([]float64, width)
+	each(func(x int, col []float64) {
+		for _, val := range col {
+			sums[x] += val
+		}
+	})
+
+	sum := 0.0
+	for _, s := range sums {
+		sum += s
+	}
+	return sum
+}
+
+// minMaxColumns finds the min and max of each column separately, then of all of the columns together
+func minMaxColumns(width int, each func(func(x int, col []float64))) (min, max float64) {
+	var (
+		mins = make([]float64, width)
+		maxs = make([]float64, width)
+	)
+	each(func(x int, col []float64) {
+		if len(col) == 0 {
+			return
+		}
+		mins[x], maxs[x] = col[0], col[0]
+		for _, val := range col {
+			mins[x] = math.Min(mins[x], val)
+			maxs[x] = math.Max(maxs[x], val)
+		}
+	})
+
+	if width == 0 {
+		return
+	}
+	min, max = mins[0], maxs[0]
+	for x := range mins {
+		min = math.Min(min, mins[x])
+		max = math.Max(max, maxs[x])
+	}
+	return
+}
@@ -0,0 +1,197 @@
+package zmath
+
+import "math"
+
+// Filter is how values are found between the points of a Map, when sampling or resizing it
+type Filter int
+
+// Filters
+const (
+	FilterNearest  Filter = iota // the value of the nearest point
+	FilterBilinear               // linear interpolation between the 2x2 nearest points
+	FilterBicubic                // Catmull-Rom interpolation between the 4x4 nearest points, which is sharper than bilinear
+	FilterLanczos3               // windowed sinc interpolation between the 6x6 nearest points; the sharpest, but it may ring around hard edges
+	FilterArea                   // the mean of everything each sample covers, weighted by how much of each point it covers; the best for shrinking
+)
+
+// reach returns how far from the sample the filter has any weight, when stretched by scale
+func (f Filter) reach(scale float64) float64 {
+	switch f {
+	case FilterBilinear:
+		return scale
+	case FilterBicubic:
+		return 2 * scale
+	case FilterLanczos3:
+		return 3 * scale
+	case FilterArea:
+		return (scale + 1) / 2
+	default:
+		return 0.5
+	}
+}
+
+// weight returns the filter's weight of a point d away from the sample, when stretched by scale
+func (f Filter) weight(d, scale float64) float64 {
+	if f == FilterArea {
+		// how much of the point's pixel falls within the sample's
+		return math.Max(0, math.Min(d+0.5, scale/2)-math.Max(d-0.5, -scale/2))
+	}
+
+	d = math.Abs(d / scale)
+	switch f {
+	case FilterBilinear:
+		return math.Max(0, 1-d)
+	case FilterBicubic:
+		const a = -0.5
+		if d < 1 {
+			return (a+2)*d*d*d - (a+3)*d*d + 1
+		} else if d < 2 {
+			return a*d*d*d - 5*a*d*d + 8*a*d - 4*a
+		}
+		return 0
+	case FilterLanczos3:
+		if d == 0 {
+			return 1
+		} else if d >= 3 {
+			return 0
+		}
+		return 3 * math.Sin(math.Pi*d) * math.Sin(math.Pi*d/3) / (math.Pi * math.Pi * d * d)
+	default:
+		return 0
+	}
+}
+
+// taps holds the points of a line that make up one sample, and how much of each
+type taps struct {
+	index  []int
+	weight []float64
+}
+
+// set fills the taps with the points of a line of length n that make up a sample at center, with the filter
+// stretched by scale. The weights sum to 1.
+func (t *taps) set(f Filter, center, scale float64, n int, edge EdgeMode) {
+	t.index, t.weight = t.index[:0], t.weight[:0]
+	if f == FilterNearest {
+		if i := edgeIndex(int(math.Floor(center+0.5)), n, edge); i >= 0 {
+			t.index, t.weight = append(t.index, i), append(t.weight, 1)
+		}
+		return
+	}
+
+	var (
+		reach = f.reach(scale)
+		lo    = int(math.Ceil(center - reach))
+		hi    = int(math.Floor(center + reach))
+		sum   = 0.0
+	)
+	for j := lo; j <= hi; j++ {
+		w := f.weight(float64(j)-center, scale)
+		if w == 0 {
+			continue
+		}
+		i := edgeIndex(j, n, edge)
+		if i < 0 {
+			continue
+		}
+		t.index, t.weight = append(t.index, i), append(t.weight, w)
+		sum += w
+	}
+	if sum != 0 {
+		for i := range t.weight {
+			t.weight[i] /= sum
+		}
+	}
+}
+
+// Sample returns the value of the Map at any position, found by bilinear interpolation between the four nearest
+// points. Each point of the Map sits at its own integer coordinates, and positions past the edges are clamped.
+func (m Map) Sample(pos Vec) float64 {
+	return m.SampleFilter(pos, FilterBilinear, EdgeClamp)
+}
+
+// SampleFilter returns the value of the Map at any position, found with the passed Filter. Positions past the
+// edges are treated according to edge; with EdgeRenormalize, only points on the Map are weighted, and 0 is
+// returned once the position is too far off of the Map to reach any of them.
+func (m Map) SampleFilter(pos Vec, filter Filter, edge EdgeMode) float64 {
+	var (
+		bounds = m.Bounds()
+		xBuf   [8]int
+		yBuf   [8]int
+		xwBuf  [8]float64
+		ywBuf  [8]float64
+		tx     = taps{xBuf[:0], xwBuf[:0]}
+		ty     = taps{yBuf[:0], ywBuf[:0]}
+		sum    = 0.0
+		weight = 0.0
+	)
+	tx.set(filter, pos.X, 1, bounds.X, edge)
+	ty.set(filter, pos.Y, 1, bounds.Y, edge)
+	for i, x := range tx.index {
+		for j, y := range ty.index {
+			w := tx.weight[i] * ty.weight[j]
+			sum += w * m[x][y]
+			weight += w
+		}
+	}
+	if weight == 0 {
+		return 0
+	}
+	return sum / weight
+}
+
+// Resize returns a NEW Map of the passed bounds, resampled from the Map with the passed Filter. When shrinking,
+// the filter is stretched to cover every point of the Map that falls within each new point, so that nothing is
+// skipped over, except with FilterNearest.
+func (m Map) Resize(bounds VecInt, filter Filter) Map {
+	var (
+		old  = m.Bounds()
+		wide = NewMap(VI(bounds.X, old.Y), 0) // resized along x only
+		out  = NewMap(bounds, 0)
+	)
+	if old.X == 0 || old.Y == 0 {
+		return out
+	}
+
+	xs := resizeTaps(filter, old.X, bounds.X)
+	ParallelFor(bounds.X, func(x int) {
+		t := xs[x]
+		for y := range wide[x] {
+			sum := 0.0
+			for i, src := range t.index {
+				sum += t.weight[i] * m[src][y]
+			}
+			wide[x][y] = sum
+		}
+	})
+
+	ys := resizeTaps(filter, old.Y, bounds.Y)
+	ParallelFor(bounds.X, func(x int) {
+		for y := range out[x] {
+			var (
+				t   = ys[y]
+				sum = 0.0
+			)
+			for i, src := range t.index {
+				sum += t.weight[i] * wide[x][src]
+			}
+			out[x][y] = sum
+		}
+	})
+
+	return out
+}
+
+// resizeTaps returns the taps that make up every point of a line of length n, resized to length to. The outer
+// edges of both lines line up, so that nothing is shifted.
+func resizeTaps(filter Filter, n, to int) []taps {
+	var (
+		scale   = float64(n) / float64(to)
+		stretch = math.Max(scale, 1)
+		all     = make([]taps, to)
+	)
+	for i := range all {
+		center := (float64(i)+0.5)*scale - 0.5
+		all[i].set(filter, center, stretch, n, EdgeClamp)
+	}
+	return all
+}
@@ -0,0 +1,227 @@
+package zmath
+
+import "math"
+
+// Transform is a projective transformation of the plane, as a 3x3 matrix acting on (x, y, 1). Affine
+// transformations (rotating, scaling, shearing and translating) leave the bottom row as (0, 0, 1).
+// Note that Transform's member functions will NOT modify the underlying Transform when called!
+type Transform [3][3]float64
+
+// Identity returns a Transform that leaves everything where it is
+func Identity() Transform {
+	return Transform{
+		{1, 0, 0},
+		{0, 1, 0},
+		{0, 0, 1},
+	}
+}
+
+// Affine returns the affine Transform that moves (x, y) to (a*x + b*y + c, d*x + e*y + f)
+func Affine(a, b, c, d, e, f float64) Transform {
+	return Transform{
+		{a, b, c},
+		{d, e, f},
+		{0, 0, 1},
+	}
+}
+
+// Translation returns a Transform that moves everything by v
+func Translation(v Vec) Transform {
+	return Affine(1, 0, v.X, 0, 1, v.Y)
+}
+
+// Scaling returns a Transform that scales everything away from the origin, by s.X along x and s.Y along y
+func Scaling(s Vec) Transform {
+	return Affine(s.X, 0, 0, 0, s.Y, 0)
+}
+
+// Rotation returns a Transform that rotates everything about the origin by angle radians, from +x toward +y.
+// In an image, where y points down, that's clockwise.
+func Rotation(angle float64) Transform {
+	sin, cos := math.Sincos(angle)
+	return Affine(cos, -sin, 0, sin, cos, 0)
+}
+
+// Projective returns the Transform that moves each of the four from points to the matching to point, e.g. the
+// corners of an image to the corners of a quadrilateral. It returns false if three of the points in either set
+// lie on the same line.
+func Projective(from, to [4]Vec) (Transform, bool) {
+	src, ok1 := squareTo(from)
+	dst, ok2 := squareTo(to)
+	if !ok1 || !ok2 {
+		return Identity(), false
+	}
+	inv, ok := src.Inverse()
+	return inv.Then(dst), ok
+}
+
+// squareTo returns the Transform that moves the corners of the unit square, (0, 0), (1, 0), (1, 1) and (0, 1),
+// to the four passed points
+func squareTo(pts [4]Vec) (Transform, bool) {
+	var (
+		d1  = pts[1].Subtract(pts[2])
+		d2  = pts[3].Subtract(pts[2])
+		sum = pts[0].Subtract(pts[1]).Add(pts[2]).Subtract(pts[3])
+		det = d1.X*d2.Y - d2.X*d1.Y
+	)
+	if det == 0 {
+		return Identity(), false
+	}
+	var (
+		g = (sum.X*d2.Y - d2.X*sum.Y) / det
+		h = (d1.X*sum.Y - sum.X*d1.Y) / det
+	)
+	return Transform{
+		{pts[1].X - pts[0].X + g*pts[1].X, pts[3].X - pts[0].X + h*pts[3].X, pts[0].X},
+		{pts[1].Y - pts[0].Y + g*pts[1].Y, pts[3].Y - pts[0].Y + h*pts[3].Y, pts[0].Y},
+		{g, h, 1},
+	}, true
+}
+
+// Then returns the Transform that does the called Transform first, then the passed one
+func (t Transform) Then(next Transform) Transform {
+	var out Transform
+	for i := range out {
+		for j := range out[i] {
+			for k := 0; k < 3; k++ {
+				out[i][j] += next[i][k] * t[k][j]
+			}
+		}
+	}
+	return out
+}
+
+// Apply returns where the Transform moves v to
+func (t Transform) Apply(v Vec) Vec {
+	var (
+		x = t[0][0]*v.X + t[0][1]*v.Y + t[0][2]
+		y = t[1][0]*v.X + t[1][1]*v.Y + t[1][2]
+		w = t[2][0]*v.X + t[2][1]*v.Y + t[2][2]
+	)
+	return V(x/w, y/w)
+}
+
+// Inverse returns the Transform that undoes the called one, and false if there isn't one
+func (t Transform) Inverse() (Transform, bool) {
+	var (
+		c00 = t[1][1]*t[2][2] - t[1][2]*t[2][1]
+		c01 = t[1][2]*t[2][0] - t[1][0]*t[2][2]
+		c02 = t[1][0]*t[2][1] - t[1][1]*t[2][0]
+		det = t[0][0]*c00 + t[0][1]*c01 + t[0][2]*c02
+	)
+	if det == 0 {
+		return Identity(), false
+	}
+	inv := Transform{
+		{c00, t[0][2]*t[2][1] - t[0][1]*t[2][2], t[0][1]*t[1][2] - t[0][2]*t[1][1]},
+		{c01, t[0][0]*t[2][2] - t[0][2]*t[2][0], t[0][2]*t[1][0] - t[0][0]*t[1][2]},
+		{c02, t[0][1]*t[2][0] - t[0][0]*t[2][1], t[0][0]*t[1][1] - t[0][1]*t[1][0]},
+	}
+	for i := range inv {
+		for j := range inv[i] {
+			inv[i][j] /= det
+		}
+	}
+	return inv, true
+}
+
+//                               //
+// - - - TRANSFORMING MAPS - - - //
+//                               //
+
+// RotateCW90 returns a NEW Map, rotated 90 degrees clockwise as seen in an image, where y points down
+func (m Map) RotateCW90() Map {
+	var (
+		bounds  = m.Bounds()
+		rotated = NewMap(VI(bounds.Y, bounds.X), 0)
+	)
+	rotated.columns(func(x int, col []float64) {
+		for y := range col {
+			col[y] = m[y][bounds.Y-1-x]
+		}
+	})
+	return rotated
+}
+
+// RotateCCW90 returns a NEW Map, rotated 90 degrees counterclockwise as seen in an image, where y points down
+func (m Map) RotateCCW90() Map {
+	var (
+		bounds  = m.Bounds()
+		rotated = NewMap(VI(bounds.Y, bounds.X), 0)
+	)
+	rotated.columns(func(x int, col []float64) {
+		for y := range col {
+			col[y] = m[bounds.X-1-y][x]
+		}
+	})
+	return rotated
+}
+
+// Rotate180 returns a NEW Map, rotated 180 degrees
+func (m Map) Rotate180() Map {
+	return m.CopyAll().FlipHorizontal().FlipVertical()
+}
+
+// Rotate returns a NEW Map of the same bounds, rotated about its center by angle radians, clockwise as seen in
+// an image. The corners that rotate out of bounds are cut off, and the parts of the new Map that nothing rotates
+// into are filled according to edge; EdgeRenormalize leaves them 0.
+func (m Map) Rotate(angle float64, filter Filter, edge EdgeMode) Map {
+	center := m.Bounds().V().Subtract(V(1, 1)).Scale(0.5)
+	t := Translation(center.Scale(-1)).Then(Rotation(angle)).Then(Translation(center))
+	return m.Warp(t, m.Bounds(), filter, edge)
+}
+
+// Warp returns a NEW Map of the passed bounds, where every point of the Map has been moved to wherever the
+// Transform moves it. Each new point is sampled from the Map with the passed Filter, and points past the
+// Map's edges are treated according to edge, as in SampleFilter. If the Transform can't be undone, e.g. because
+// it squashes everything onto a line, the new Map is left all 0.
+func (m Map) Warp(t Transform, bounds VecInt, filter Filter, edge EdgeMode) Map {
+	warped := NewMap(bounds, 0)
+	inv, ok := t.Inverse()
+	if !ok || len(m) == 0 || len(m[0]) == 0 {
+		return warped
+	}
+	warped.columns(func(x int, col []float64) {
+		for y := range col {
+			col[y] = m.SampleFilter(inv.Apply(V(float64(x), float64(y))), filter, edge)
+		}
+	})
+	return warped
+}
+
+// Crop returns a NEW Map of the part of the Map from min, inclusive, to max, exclusive, cut down to whatever of
+// that lies within the Map. Unlike Copy, nothing out of bounds is ever included, so if none of it lies within
+// the Map, the new Map is empty and its Bounds are ZVI.
+func (m Map) Crop(min, max VecInt) Map {
+	bounds := m.Bounds()
+	min = VI(MaxInt(min.X, 0), MaxInt(min.Y, 0))
+	max = VI(MinInt(max.X, bounds.X), MinInt(max.Y, bounds.Y))
+	if max.X <= min.X || max.Y <= min.Y {
+		return NewMap(ZVI, 0)
+	}
+	return m.Copy(min, max)
+}
+
+// Pad returns a NEW Map with before.X and before.Y points added before the start of each axis and after.X and
+// after.Y added past the end. The new points are filled according to edge; EdgeRenormalize leaves them 0.
+func (m Map) Pad(before, after VecInt, edge EdgeMode) Map {
+	var (
+		bounds = m.Bounds()
+		padded = NewMap(bounds.Add(before).Add(after), 0)
+	)
+	if bounds.X == 0 || bounds.Y == 0 {
+		return padded
+	}
+	padded.columns(func(x int, col []float64) {
+		px := edgeIndex(x-before.X, bounds.X, edge)
+		if px < 0 {
+			return
+		}
+		for y := range col {
+			if py := edgeIndex(y-before.Y, bounds.Y, edge); py >= 0 {
+				col[y] = m[px][py]
+			}
+		}
+	})
+	return padded
+}
@@ -0,0 +1,68 @@
+package zbits
+
+import "math"
+
+// Float16bits converts a float32 to the bits of the nearest IEEE 754 half-precision float, rounding to even.
+// Values too large for a half become infinity, and values too small become zero (or a subnormal).
+func Float16bits(f float32) uint16 {
+	var (
+		bits = math.Float32bits(f)
+		sign = uint16(bits>>16) & 0x8000
+		exp  = int((bits>>23)&0xff) - 127 + 15
+		mant = bits & 0x7fffff
+	)
+
+	switch {
+	case (bits>>23)&0xff == 0xff: // Inf or NaN
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp >= 0x1f: // overflow
+		return sign | 0x7c00
+	case exp <= 0: // subnormal or zero
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		half := uint32(1) << (shift - 1)
+		rounded := mant >> shift
+		if rem := mant & (half<<1 - 1); rem > half || (rem == half && rounded&1 == 1) {
+			rounded++
+		}
+		return sign | uint16(rounded)
+	}
+
+	rounded := uint32(exp)<<10 | mant>>13
+	if rem := mant & 0x1fff; rem > 0x1000 || (rem == 0x1000 && rounded&1 == 1) {
+		rounded++ // may carry into the exponent, which correctly rounds up to the next power of 2 or to infinity
+	}
+	return sign | uint16(rounded)
+}
+
+// Float16frombits converts the bits of an IEEE 754 half-precision float to a float32
+func Float16frombits(b uint16) float32 {
+	var (
+		sign = uint32(b&0x8000) << 16
+		exp  = uint32(b>>10) & 0x1f
+		mant = uint32(b & 0x3ff)
+	)
+
+	switch exp {
+	case 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// subnormal: normalize
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		return math.Float32frombits(sign | e<<23 | (mant&0x3ff)<<13)
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
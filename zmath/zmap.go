@@ -0,0 +1,670 @@
+package zmath
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+
+	"github.com/Isarcus/zarks/system"
+	"github.com/Isarcus/zarks/zmath/zbits"
+)
+
+// .zmap file layout:
+//
+//	Header   (64)  magic "ZMAP", version, element type, byte order, metadata length, checksum, compression,
+//	               quantization range, reserved zeroes
+//	Bounds    (8)  width and height as uint32
+//	Metadata (n)   seed, then length-prefixed generator and config strings
+//	Data    (w*h)  one element per cell, column by column, optionally deflate-compressed
+//
+// Header fields are always little-endian; bounds, metadata and data use the byte order named in the header.
+// The checksum is a CRC-32 (IEEE) of everything after the header, before compression. Files written before the
+// header was used have a header of all zeroes, followed by little-endian bounds and float64 data with no metadata.
+
+// ZMapVersion is the newest .zmap format version this package reads and writes.
+// Version 2 added compression and the Float16 and Uint16 element types.
+const ZMapVersion uint16 = 2
+
+const (
+	zmapHeaderSize = 64
+	zmapMagic      = "ZMAP"
+
+	// The largest bounds a .zmap may have, so that a corrupt file can't ask for more memory than any Map could
+	// reasonably need. 1<<20 float64s is 8 MiB per column.
+	zmapMaxDim   = 1 << 20
+	zmapMaxCells = 1 << 31
+)
+
+// ElemType identifies how each cell of a Map is stored in a .zmap file
+type ElemType uint8
+
+// Element types
+const (
+	Float64 ElemType = iota + 1
+	Float32
+	Float16
+	Uint16 // quantized linearly between the Map's min and max, which are stored in the header
+)
+
+// Size returns the number of bytes used to store one element of the given type
+func (t ElemType) Size() int {
+	switch t {
+	case Float16, Uint16:
+		return 2
+	case Float32:
+		return 4
+	case Float64:
+		return 8
+	}
+	return 0
+}
+
+// Compression identifies how the data section of a .zmap file is compressed
+type Compression uint8
+
+// Compression types
+const (
+	CompressNone Compression = iota
+	CompressDeflate
+)
+
+// Errors returned when reading a .zmap file
+var (
+	ErrNotZMap        = errors.New("zmap: not a .zmap file")
+	ErrVersion        = errors.New("zmap: unsupported format version")
+	ErrTruncated      = errors.New("zmap: file is truncated")
+	ErrChecksum       = errors.New("zmap: checksum mismatch")
+	ErrBadElement     = errors.New("zmap: unknown element type")
+	ErrBadCompression = errors.New("zmap: unknown compression")
+	ErrBadBounds      = errors.New("zmap: invalid bounds")
+)
+
+// ZMapMeta is optional information about how a Map was made, stored alongside it in a .zmap file
+type ZMapMeta struct {
+	Generator string // e.g. "simplex"
+	Seed      int64
+	Config    string // free-form description of the generator's settings
+}
+
+// ZMapHeader describes how a Map is (or should be) stored in a .zmap file
+type ZMapHeader struct {
+	Version     uint16           // ignored when saving; the newest version is always written
+	Elem        ElemType         // Float64 if left zero
+	Order       binary.ByteOrder // binary.LittleEndian if left nil
+	Compression Compression
+	Meta        ZMapMeta
+
+	QuantMin, QuantMax float64 // Uint16 only; computed from the Map when saving
+	Legacy             bool    // set when loading a file written with an all-zero header
+}
+
+// DefaultZMapHeader stores full-precision, uncompressed, little-endian data with no metadata
+var DefaultZMapHeader = ZMapHeader{
+	Version: ZMapVersion,
+	Elem:    Float64,
+	Order:   binary.LittleEndian,
+}
+
+func (hdr *ZMapHeader) checkDefaults() {
+	hdr.Version = ZMapVersion
+	if hdr.Elem == 0 {
+		hdr.Elem = DefaultZMapHeader.Elem
+	}
+	if hdr.Order == nil {
+		hdr.Order = DefaultZMapHeader.Order
+	}
+}
+
+//                      //
+// - - - WRITING - - -  //
+//                      //
+
+// Save saves a Map as full-precision binary data at the path specified. File ending should be .zmap
+func (m Map) Save(path string) error {
+	return m.SaveAs(path, DefaultZMapHeader)
+}
+
+// SaveAs saves a Map at the path specified, using the element type, byte order, compression and metadata of
+// the passed header.
+func (m Map) SaveAs(path string, hdr ZMapHeader) error {
+	f := system.CreateFile(path)
+	if f == nil {
+		return fmt.Errorf("zmap: save to %v aborted", path)
+	}
+	defer f.Close()
+
+	return m.Encode(f, hdr)
+}
+
+// Encode writes a Map to w in the .zmap format, using the element type, byte order, compression and metadata
+// of the passed header. Writes are buffered, so w need not be. If w is an io.WriteSeeker, the data is streamed
+// straight to it and the checksum is filled in afterwards; otherwise the encoded data is held in memory until
+// it can be written after the header.
+func (m Map) Encode(w io.Writer, hdr ZMapHeader) error {
+	hdr.checkDefaults()
+	if hdr.Elem.Size() == 0 {
+		return ErrBadElement
+	}
+	if hdr.Compression != CompressNone && hdr.Compression != CompressDeflate {
+		return ErrBadCompression
+	}
+	if len(m) == 0 || len(m[0]) == 0 {
+		return fmt.Errorf("%w %vx0", ErrBadBounds, len(m))
+	}
+	if hdr.Elem == Uint16 {
+		hdr.QuantMin, hdr.QuantMax = m.GetMinMax()
+	}
+
+	var (
+		meta     = encodeZMapMeta(hdr.Meta, hdr.Order)
+		preamble = make([]byte, 8, 8+len(meta))
+		bounds   = m.Bounds()
+	)
+	if err := checkZMapBounds(uint64(bounds.X), uint64(bounds.Y)); err != nil {
+		return err
+	}
+	hdr.Order.PutUint32(preamble[0:4], uint32(bounds.X))
+	hdr.Order.PutUint32(preamble[4:8], uint32(bounds.Y))
+	preamble = append(preamble, meta...)
+
+	// Header (64), with the checksum filled in once the data has been written
+	const checksumAt = 12
+	header := [zmapHeaderSize]byte{}
+	copy(header[0:4], zmapMagic)
+	binary.LittleEndian.PutUint16(header[4:6], hdr.Version)
+	header[6] = byte(hdr.Elem)
+	if hdr.Order == binary.BigEndian {
+		header[7] = 1
+	}
+	binary.LittleEndian.PutUint32(header[8:12], uint32(len(meta)))
+	header[16] = byte(hdr.Compression)
+	binary.LittleEndian.PutUint64(header[24:32], math.Float64bits(hdr.QuantMin))
+	binary.LittleEndian.PutUint64(header[32:40], math.Float64bits(hdr.QuantMax))
+
+	crc := crc32.NewIEEE()
+	crc.Write(preamble)
+
+	// If w can seek, the checksum is patched into the header after the data has been written. Otherwise the
+	// encoded data is held onto until the checksum is known.
+	if ws, ok := w.(io.WriteSeeker); ok {
+		if start, err := ws.Seek(0, io.SeekCurrent); err == nil {
+			bw := bufio.NewWriter(ws)
+			bw.Write(header[:])
+			bw.Write(preamble)
+			if err := m.encodeBody(bw, crc, hdr); err != nil {
+				return err
+			}
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+
+			end, err := ws.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			if _, err := ws.Seek(start+checksumAt, io.SeekStart); err != nil {
+				return err
+			}
+			binary.LittleEndian.PutUint32(header[checksumAt:], crc.Sum32())
+			if _, err := ws.Write(header[checksumAt : checksumAt+4]); err != nil {
+				return err
+			}
+			_, err = ws.Seek(end, io.SeekStart)
+			return err
+		}
+	}
+
+	var data bytes.Buffer
+	if err := m.encodeBody(&data, crc, hdr); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(header[checksumAt:], crc.Sum32())
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header[:])
+	bw.Write(preamble)
+	data.WriteTo(bw)
+	return bw.Flush()
+}
+
+// encodeBody writes the Map's data to w, compressed if the header says so. The data is added to crc as it was
+// before compression.
+func (m Map) encodeBody(w io.Writer, crc io.Writer, hdr ZMapHeader) error {
+	if hdr.Compression != CompressDeflate {
+		return m.encodeData(io.MultiWriter(crc, w), hdr)
+	}
+	fw, err := flate.NewWriter(w, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	if err := m.encodeData(io.MultiWriter(crc, fw), hdr); err != nil {
+		return err
+	}
+	return fw.Close()
+}
+
+// encodeData writes the Map's data one column at a time
+func (m Map) encodeData(w io.Writer, hdr ZMapHeader) error {
+	var (
+		size  = hdr.Elem.Size()
+		scale = 0.0
+	)
+	if rng := hdr.QuantMax - hdr.QuantMin; rng > 0 {
+		scale = math.MaxUint16 / rng
+	}
+
+	var buf []byte
+	for _, row := range m {
+		if cap(buf) < len(row)*size {
+			buf = make([]byte, len(row)*size)
+		}
+		buf = buf[:len(row)*size]
+
+		for y, val := range row {
+			b := buf[y*size:]
+			switch hdr.Elem {
+			case Float64:
+				hdr.Order.PutUint64(b, math.Float64bits(val))
+			case Float32:
+				hdr.Order.PutUint32(b, math.Float32bits(float32(val)))
+			case Float16:
+				hdr.Order.PutUint16(b, zbits.Float16bits(float32(val)))
+			case Uint16:
+				hdr.Order.PutUint16(b, uint16(MinMax(0, math.MaxUint16, math.Round((val-hdr.QuantMin)*scale))))
+			}
+		}
+
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//                      //
+// - - - READING - - -  //
+//                      //
+
+// MapFromPath loads a Map from the target path
+func MapFromPath(path string) (Map, error) {
+	m, _, err := LoadMap(path)
+	return m, err
+}
+
+// LoadMap loads a Map from the target path, along with the header describing how it was stored.
+// Truncated files, files with a bad checksum and files that aren't .zmaps at all are rejected with an error.
+func LoadMap(path string) (Map, ZMapHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, ZMapHeader{}, err
+	}
+	defer f.Close()
+
+	return DecodeMap(f)
+}
+
+// LoadMapRect loads only the part of the Map at the target path that lies within rect. As with Map.Copy, any
+// part of rect outside the stored Map's bounds is set to zero. Uncompressed files are read by seeking directly
+// to the requested columns, in which case the checksum is not verified.
+func LoadMapRect(path string, rect RectInt) (Map, ZMapHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, ZMapHeader{}, err
+	}
+	defer f.Close()
+
+	return DecodeMapRect(f, rect)
+}
+
+// DecodeMap reads a .zmap-formatted Map from r. Reads are buffered, so r need not be.
+func DecodeMap(r io.Reader) (Map, ZMapHeader, error) {
+	return decodeZMap(r, nil)
+}
+
+// DecodeMapRect reads only the part of a .zmap-formatted Map that lies within rect. If r is an io.ReadSeeker
+// and the data is uncompressed, only the requested columns are read and the checksum is not verified.
+// Otherwise the whole stream is read, but only the requested part is kept in memory.
+func DecodeMapRect(r io.Reader, rect RectInt) (Map, ZMapHeader, error) {
+	return decodeZMap(r, &rect)
+}
+
+// zmapReader holds the state of a .zmap being decoded
+type zmapReader struct {
+	hdr        ZMapHeader
+	r          io.Reader
+	crc        hash.Hash32
+	checksum   uint32
+	dimX, dimY int
+	preamble   int64 // offset of the data section
+}
+
+func decodeZMap(r io.Reader, rect *RectInt) (Map, ZMapHeader, error) {
+	var (
+		rs, seekable = r.(io.ReadSeeker)
+		start        int64
+	)
+	if seekable {
+		var err error
+		if start, err = rs.Seek(0, io.SeekCurrent); err != nil {
+			seekable = false
+		}
+	}
+
+	br := bufio.NewReader(r)
+	zr := &zmapReader{
+		r:   br,
+		crc: crc32.NewIEEE(),
+	}
+	if err := zr.readPreamble(); err != nil {
+		return nil, zr.hdr, err
+	}
+
+	full := RectInt{Max: VI(zr.dimX, zr.dimY)}
+	if rect == nil {
+		rect = &full
+	}
+	rect = RI(rect.Min, rect.Max)
+
+	if zr.hdr.Compression == CompressDeflate {
+		fr := flate.NewReader(br)
+		defer fr.Close()
+		zr.r = fr
+	} else if seekable {
+		zr.preamble += start
+		if err := zr.checkLength(rs); err != nil {
+			return nil, zr.hdr, err
+		}
+		if *rect != full {
+			m, err := zr.readSeek(rs, *rect)
+			return m, zr.hdr, err
+		}
+	}
+
+	m, err := zr.readStream(*rect)
+	return m, zr.hdr, err
+}
+
+// readFull reads exactly len(buf) bytes, reporting any shortfall as ErrTruncated
+func (zr *zmapReader) readFull(buf []byte) error {
+	if _, err := io.ReadFull(zr.r, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrTruncated
+		}
+		return err
+	}
+	zr.crc.Write(buf)
+	return nil
+}
+
+// readPreamble reads everything before the data section: the header, bounds and metadata
+func (zr *zmapReader) readPreamble() error {
+	// Header (64)
+	header := [zmapHeaderSize]byte{}
+	n, err := io.ReadFull(zr.r, header[:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if n >= 4 && string(header[0:4]) != zmapMagic && !allZero(header[0:4]) {
+		return ErrNotZMap
+	}
+	if n < zmapHeaderSize {
+		return ErrTruncated
+	}
+	if zr.hdr, zr.checksum, err = decodeZMapHeader(header[:]); err != nil {
+		return err
+	}
+	metaLen := binary.LittleEndian.Uint32(header[8:12])
+
+	// Bounds (8)
+	bounds := [8]byte{}
+	if err := zr.readFull(bounds[:]); err != nil {
+		return err
+	}
+	var (
+		dimX = uint64(zr.hdr.Order.Uint32(bounds[0:4]))
+		dimY = uint64(zr.hdr.Order.Uint32(bounds[4:8]))
+	)
+	if err := checkZMapBounds(dimX, dimY); err != nil {
+		return err
+	}
+	zr.dimX, zr.dimY = int(dimX), int(dimY)
+
+	// Metadata (n)
+	meta := make([]byte, 0, MinInt(int(metaLen), 1<<16))
+	for len(meta) < int(metaLen) {
+		chunk := make([]byte, MinInt(int(metaLen)-len(meta), 1<<16))
+		if err := zr.readFull(chunk); err != nil {
+			return err
+		}
+		meta = append(meta, chunk...)
+	}
+	zr.hdr.Meta, err = decodeZMapMeta(meta, zr.hdr.Order)
+	zr.preamble = zmapHeaderSize + 8 + int64(metaLen)
+	return err
+}
+
+// checkLength returns ErrTruncated if rs ends before the data section does, without moving where rs reads from
+func (zr *zmapReader) checkLength(rs io.ReadSeeker) error {
+	pos, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := rs.Seek(pos, io.SeekStart); err != nil {
+		return err
+	}
+
+	length := int64(zr.dimX) * int64(zr.dimY) * int64(zr.hdr.Elem.Size())
+	if end-zr.preamble < length {
+		return ErrTruncated
+	}
+	return nil
+}
+
+// readStream reads every column of the data section in order, keeping only those within rect. The stream's
+// length isn't known up front, so columns are only added to the Map as the data for them arrives.
+func (zr *zmapReader) readStream(rect RectInt) (Map, error) {
+	var (
+		size   = zr.hdr.Elem.Size()
+		buf    = make([]byte, zr.dimY*size)
+		out    = make(Map, 0, MinInt(rect.Dx(), 1<<10))
+		column = make([]float64, zr.dimY)
+	)
+	grow := func(width int) {
+		for len(out) < width {
+			out = append(out, make([]float64, rect.Dy()))
+		}
+	}
+
+	for x := 0; x < zr.dimX; x++ {
+		if err := zr.readFull(buf); err != nil {
+			return nil, err
+		}
+		if x < rect.Min.X || x >= rect.Max.X {
+			continue
+		}
+		grow(x - rect.Min.X + 1)
+		zr.decodeColumn(buf, column)
+		zr.place(out, column, x, 0, rect)
+	}
+	grow(rect.Dx()) // whatever of rect lies past the stored Map
+
+	if !zr.hdr.Legacy && zr.crc.Sum32() != zr.checksum {
+		return nil, ErrChecksum
+	}
+	return out, nil
+}
+
+// readSeek reads only the part of each column within rect, seeking past everything else
+func (zr *zmapReader) readSeek(rs io.ReadSeeker, rect RectInt) (Map, error) {
+	var (
+		size   = int64(zr.hdr.Elem.Size())
+		minY   = MaxInt(rect.Min.Y, 0)
+		maxY   = MinInt(rect.Max.Y, zr.dimY)
+		out    = NewMap(VI(rect.Dx(), rect.Dy()), 0)
+		buf    = make([]byte, MaxInt(maxY-minY, 0)*int(size))
+		column = make([]float64, len(buf)/int(size))
+	)
+
+	for x := MaxInt(rect.Min.X, 0); x < MinInt(rect.Max.X, zr.dimX) && len(buf) > 0; x++ {
+		offset := zr.preamble + (int64(x)*int64(zr.dimY)+int64(minY))*size
+		if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(rs, buf); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, ErrTruncated
+			}
+			return nil, err
+		}
+		zr.decodeColumn(buf, column)
+		zr.place(out, column, x, minY, rect)
+	}
+	return out, nil
+}
+
+// decodeColumn converts the raw bytes of (part of) a column into float64s
+func (zr *zmapReader) decodeColumn(buf []byte, column []float64) {
+	var (
+		hdr  = zr.hdr
+		size = hdr.Elem.Size()
+		step = (hdr.QuantMax - hdr.QuantMin) / math.MaxUint16
+	)
+	for y := range column {
+		b := buf[y*size:]
+		switch hdr.Elem {
+		case Float64:
+			column[y] = math.Float64frombits(hdr.Order.Uint64(b))
+		case Float32:
+			column[y] = float64(math.Float32frombits(hdr.Order.Uint32(b)))
+		case Float16:
+			column[y] = float64(zbits.Float16frombits(hdr.Order.Uint16(b)))
+		case Uint16:
+			column[y] = hdr.QuantMin + float64(hdr.Order.Uint16(b))*step
+		}
+	}
+}
+
+// place copies the part of a column (starting at row y0 of the stored Map) that lies within rect into out
+func (zr *zmapReader) place(out Map, column []float64, x, y0 int, rect RectInt) {
+	dst := out[x-rect.Min.X]
+	for i, val := range column {
+		if y := y0 + i; y >= rect.Min.Y && y < rect.Max.Y {
+			dst[y-rect.Min.Y] = val
+		}
+	}
+}
+
+// decodeZMapHeader interprets the first 64 bytes of a .zmap file
+func decodeZMapHeader(header []byte) (hdr ZMapHeader, checksum uint32, err error) {
+	if allZero(header) {
+		hdr = DefaultZMapHeader
+		hdr.Version = 0
+		hdr.Legacy = true
+		return
+	}
+	if string(header[0:4]) != zmapMagic {
+		err = ErrNotZMap
+		return
+	}
+
+	hdr.Version = binary.LittleEndian.Uint16(header[4:6])
+	if hdr.Version == 0 || hdr.Version > ZMapVersion {
+		err = fmt.Errorf("%w %v", ErrVersion, hdr.Version)
+		return
+	}
+	hdr.Elem = ElemType(header[6])
+	if hdr.Elem.Size() == 0 {
+		err = ErrBadElement
+		return
+	}
+	switch header[7] {
+	case 0:
+		hdr.Order = binary.LittleEndian
+	case 1:
+		hdr.Order = binary.BigEndian
+	default:
+		err = fmt.Errorf("zmap: unknown byte order %v", header[7])
+		return
+	}
+
+	checksum = binary.LittleEndian.Uint32(header[12:16])
+	hdr.Compression = Compression(header[16])
+	if hdr.Compression != CompressNone && hdr.Compression != CompressDeflate {
+		err = ErrBadCompression
+		return
+	}
+	hdr.QuantMin = math.Float64frombits(binary.LittleEndian.Uint64(header[24:32]))
+	hdr.QuantMax = math.Float64frombits(binary.LittleEndian.Uint64(header[32:40]))
+	return
+}
+
+func encodeZMapMeta(meta ZMapMeta, order binary.ByteOrder) []byte {
+	if meta == (ZMapMeta{}) {
+		return nil
+	}
+
+	buf := make([]byte, 8, 16+len(meta.Generator)+len(meta.Config))
+	order.PutUint64(buf, uint64(meta.Seed))
+	for _, str := range []string{meta.Generator, meta.Config} {
+		length := [4]byte{}
+		order.PutUint32(length[:], uint32(len(str)))
+		buf = append(buf, length[:]...)
+		buf = append(buf, str...)
+	}
+	return buf
+}
+
+func decodeZMapMeta(data []byte, order binary.ByteOrder) (ZMapMeta, error) {
+	var meta ZMapMeta
+	if len(data) == 0 {
+		return meta, nil
+	}
+	if len(data) < 8 {
+		return meta, ErrTruncated
+	}
+	meta.Seed = int64(order.Uint64(data))
+	data = data[8:]
+
+	for _, str := range []*string{&meta.Generator, &meta.Config} {
+		if len(data) < 4 {
+			return meta, ErrTruncated
+		}
+		length := int(order.Uint32(data))
+		data = data[4:]
+		if len(data) < length {
+			return meta, ErrTruncated
+		}
+		*str = string(data[:length])
+		data = data[length:]
+	}
+	return meta, nil
+}
+
+// checkZMapBounds returns ErrBadBounds unless a Map of the passed bounds is small enough to store in a .zmap
+func checkZMapBounds(dimX, dimY uint64) error {
+	if dimX == 0 || dimY == 0 || dimX > zmapMaxDim || dimY > zmapMaxDim || dimX*dimY > zmapMaxCells {
+		return fmt.Errorf("%w %vx%v", ErrBadBounds, dimX, dimY)
+	}
+	return nil
+}
+
+func allZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
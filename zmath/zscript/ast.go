@@ -0,0 +1,172 @@
+package zscript
+
+// node is implemented by every element of the abstract syntax tree
+type node interface {
+	position() Pos
+}
+
+// expr is a node that evaluates to an Object
+type expr interface {
+	node
+	exprNode()
+}
+
+// stmt is a node that can be executed
+type stmt interface {
+	node
+	stmtNode()
+}
+
+//                          //
+// - - - EXPRESSIONS - - -  //
+//                          //
+
+type (
+	// literal is a constant INT, FLOAT, BOOL or STRING
+	literal struct {
+		pos   Pos
+		value Object
+	}
+
+	// ident refers to a variable
+	ident struct {
+		pos  Pos
+		name string
+	}
+
+	// unaryExpr is -x or !x
+	unaryExpr struct {
+		pos Pos
+		op  tokenKind
+		x   expr
+	}
+
+	// binaryExpr is x op y
+	binaryExpr struct {
+		pos  Pos // position of the operator
+		op   tokenKind
+		x, y expr
+	}
+
+	// callExpr is name(args...)
+	callExpr struct {
+		pos  Pos
+		name string
+		args []expr
+	}
+
+	// indexExpr is x[index]; m[x][y] is an indexExpr whose x is also an indexExpr
+	indexExpr struct {
+		pos   Pos // position of the opening bracket
+		x     expr
+		index expr
+	}
+
+	// vecExpr is a vector literal, (x, y)
+	vecExpr struct {
+		pos  Pos
+		x, y expr
+	}
+)
+
+func (e *literal) position() Pos    { return e.pos }
+func (e *ident) position() Pos      { return e.pos }
+func (e *unaryExpr) position() Pos  { return e.pos }
+func (e *binaryExpr) position() Pos { return e.pos }
+func (e *callExpr) position() Pos   { return e.pos }
+func (e *indexExpr) position() Pos  { return e.pos }
+func (e *vecExpr) position() Pos    { return e.pos }
+
+func (*literal) exprNode()    {}
+func (*ident) exprNode()      {}
+func (*unaryExpr) exprNode()  {}
+func (*binaryExpr) exprNode() {}
+func (*callExpr) exprNode()   {}
+func (*indexExpr) exprNode()  {}
+func (*vecExpr) exprNode()    {}
+
+//                         //
+// - - - STATEMENTS - - -  //
+//                         //
+
+type (
+	// projStmt is PROJ name
+	projStmt struct {
+		pos  Pos
+		name string
+	}
+
+	// declStmt is Type name, or Type name = value
+	declStmt struct {
+		pos   Pos
+		typ   DataType
+		name  string
+		value expr // nil if no value was given
+	}
+
+	// assignStmt is target = value, where target is an ident or indexExpr
+	assignStmt struct {
+		pos    Pos
+		target expr
+		value  expr
+	}
+
+	// exprStmt is a function call whose result is discarded
+	exprStmt struct {
+		call *callExpr
+	}
+
+	// printStmt is PRINT args...
+	printStmt struct {
+		pos  Pos
+		args []expr
+	}
+
+	// saveStmt is SAVE map, path[, scheme]
+	saveStmt struct {
+		pos  Pos
+		args []expr
+	}
+
+	// forAllStmt is FORALL map value [x y] ... END
+	forAllStmt struct {
+		pos  Pos
+		m    expr
+		vars []string
+		body []stmt
+	}
+
+	// funcStmt is FUNC name(params...) ... END
+	funcStmt struct {
+		pos    Pos
+		name   string
+		params []string
+		body   []stmt
+	}
+
+	// returnStmt is RETURN value
+	returnStmt struct {
+		pos   Pos
+		value expr
+	}
+)
+
+func (s *projStmt) position() Pos   { return s.pos }
+func (s *declStmt) position() Pos   { return s.pos }
+func (s *assignStmt) position() Pos { return s.pos }
+func (s *exprStmt) position() Pos   { return s.call.pos }
+func (s *printStmt) position() Pos  { return s.pos }
+func (s *saveStmt) position() Pos   { return s.pos }
+func (s *forAllStmt) position() Pos { return s.pos }
+func (s *funcStmt) position() Pos   { return s.pos }
+func (s *returnStmt) position() Pos { return s.pos }
+
+func (*projStmt) stmtNode()   {}
+func (*declStmt) stmtNode()   {}
+func (*assignStmt) stmtNode() {}
+func (*exprStmt) stmtNode()   {}
+func (*printStmt) stmtNode()  {}
+func (*saveStmt) stmtNode()   {}
+func (*forAllStmt) stmtNode() {}
+func (*funcStmt) stmtNode()   {}
+func (*returnStmt) stmtNode() {}
@@ -0,0 +1,556 @@
+package zscript
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Isarcus/zarks/system"
+	"github.com/Isarcus/zarks/zimg"
+	"github.com/Isarcus/zarks/zmath"
+	"github.com/Isarcus/zarks/zmath/noise"
+)
+
+// builtin is a function that zscript code can call by name
+type builtin func(args []Object) (Object, error)
+
+// builtins contains every function available to zscript code. Math builtins always return new values,
+// while the Map builtins (interpolate, blur, ...) modify the passed Map in place and return it, just like the
+// zmath.Map functions they wrap.
+var builtins = map[string]builtin{
+	// math
+	"abs":   unary(math.Abs),
+	"sqrt":  unary(math.Sqrt),
+	"floor": unary(math.Floor),
+	"sin":   unary(math.Sin),
+	"cos":   unary(math.Cos),
+	"min":   minMax(math.Min, zmath.MinInt, zmath.Map.GetMin, zmath.Set.GetMin),
+	"max":   minMax(math.Max, zmath.MaxInt, zmath.Map.GetMax, zmath.Set.GetMax),
+
+	// conversions and constructors
+	"int":    func(args []Object) (Object, error) { return convertArg(args, Int) },
+	"float":  func(args []Object) (Object, error) { return convertArg(args, Float) },
+	"vec":    newVec,
+	"vecint": newVecInt,
+	"map":    newMap,
+	"set":    newSet,
+
+	// Maps and Sets
+	"len":         setLen,
+	"sum":         stat(zmath.Map.GetSum, setSum),
+	"mean":        stat(zmath.Map.GetMean, zmath.Set.GetMean),
+	"bounds":      mapBounds,
+	"at":          mapAt,
+	"put":         mapPut,
+	"copy":        mapCopy,
+	"interpolate": mapInterpolate,
+	"clamp":       mapClamp,
+	"blur":        mapBlur,
+	"uniform":     mapMod(zmath.Map.MakeUniform),
+	"flipv":       mapMod(zmath.Map.FlipVertical),
+	"fliph":       mapMod(zmath.Map.FlipHorizontal),
+	"slope":       mapMod(zmath.Map.GetSlopeMap),
+	"load":        mapLoad,
+
+	// noise
+	"simplex":   noiseGen(noise.NewSimplexMap),
+	"perlin":    noiseGen(noise.NewPerlinMap),
+	"worley":    noiseGen(noise.NewWorleyMap),
+	"ridgeplex": noiseGen(noise.Ridgeplex),
+	"riverplex": noiseGen(noise.Riverplex),
+}
+
+// schemes are the ColorSchemes that SAVE accepts by name
+var schemes = map[string]zimg.ColorScheme{
+	"grayscale":     zimg.SchemeGrayscale,
+	"christmas":     zimg.SchemeChristmas,
+	"rainbowflames": zimg.SchemeRainbowFlames,
+	"clouds":        zimg.SchemeClouds,
+	"rivers":        zimg.SchemeRivers,
+	"terrain":       zimg.CustomSmoothScheme(zimg.ColorSetTerrain2, zimg.ThresholdSetTerrain2),
+	"rainbow":       zimg.CustomSmoothScheme(zimg.ColorSetRainbow, zimg.ThresholdSetRainbow),
+}
+
+//                          //
+// - - - ARITHMETIC - - -   //
+//                          //
+
+// combine applies a binary operation to numbers, Vecs, VecInts, Sets and Maps. Two Ints use intOp, if it
+// isn't nil; any other combination of numbers uses floatOp. A compound value and a number are combined element
+// by element, as are two compound values of the same type and size.
+func combine(a, b Object, floatOp func(a, b float64) float64, intOp func(a, b int) (int, error)) (Object, error) {
+	var (
+		af, aErr = toFloat(a)
+		bf, bErr = toFloat(b)
+	)
+
+	// number, number
+	if aErr == nil && bErr == nil {
+		ai, aInt := a.(INT)
+		bi, bInt := b.(INT)
+		if aInt && bInt && intOp != nil {
+			i, err := intOp(int(ai), int(bi))
+			return INT(i), err
+		}
+		return FLOAT(floatOp(af, bf)), nil
+	}
+
+	// compound, number
+	if bErr == nil {
+		if vi, ok := a.(VECINT); ok && intOp != nil {
+			if bi, ok := b.(INT); ok {
+				x, errX := intOp(vi.X, int(bi))
+				y, errY := intOp(vi.Y, int(bi))
+				return VECINT{x, y}, firstError(errX, errY)
+			}
+		}
+		if obj, ok := elementwise(a, func(x float64) float64 { return floatOp(x, bf) }); ok {
+			return obj, nil
+		}
+	}
+
+	// number, compound
+	if aErr == nil {
+		if obj, ok := elementwise(b, func(x float64) float64 { return floatOp(af, x) }); ok {
+			return obj, nil
+		}
+	}
+
+	// compound, compound
+	switch av := a.(type) {
+	case VECINT:
+		if bv, ok := b.(VECINT); ok && intOp != nil {
+			x, errX := intOp(av.X, bv.X)
+			y, errY := intOp(av.Y, bv.Y)
+			return VECINT{x, y}, firstError(errX, errY)
+		}
+		if conv, err := convert(b, Vec); err == nil {
+			return combine(VEC(zmath.VecInt(av).V()), conv, floatOp, intOp)
+		}
+	case VEC:
+		if conv, err := convert(b, Vec); err == nil {
+			bv := conv.(VEC)
+			return VEC{floatOp(av.X, bv.X), floatOp(av.Y, bv.Y)}, nil
+		}
+	case SET:
+		if bv, ok := b.(SET); ok {
+			if len(av) != len(bv) {
+				return nil, fmt.Errorf("Set lengths %v and %v don't match", len(av), len(bv))
+			}
+			out := make(SET, len(av))
+			for i := range av {
+				out[i] = floatOp(av[i], bv[i])
+			}
+			return out, nil
+		}
+	case MAP:
+		if bv, ok := b.(MAP); ok {
+			if len(av) == 0 || len(bv) == 0 || zmath.Map(av).Bounds() != zmath.Map(bv).Bounds() {
+				return nil, fmt.Errorf("Map bounds %v and %v don't match", format(av), format(bv))
+			}
+			out := zmath.Map(av).CopyAll()
+			for x, row := range out {
+				for y := range row {
+					row[y] = floatOp(row[y], bv[x][y])
+				}
+			}
+			return MAP(out), nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot combine %v and %v", a.Type(), b.Type())
+}
+
+// elementwise returns a copy of a Vec, Set or Map with modFunc applied to each of its elements
+func elementwise(o Object, modFunc func(float64) float64) (Object, bool) {
+	switch v := o.(type) {
+	case VEC:
+		return VEC{modFunc(v.X), modFunc(v.Y)}, true
+	case VECINT:
+		return VEC{modFunc(float64(v.X)), modFunc(float64(v.Y))}, true
+	case SET:
+		out := make(SET, len(v))
+		for i := range v {
+			out[i] = modFunc(v[i])
+		}
+		return out, true
+	case MAP:
+		if len(v) == 0 {
+			return MAP{}, true
+		}
+		return MAP(zmath.Map(v).CopyAll().CustomMod(modFunc)), true
+	}
+	return nil, false
+}
+
+func unary(modFunc func(float64) float64) builtin {
+	return func(args []Object) (Object, error) {
+		if err := argCount(args, 1); err != nil {
+			return nil, err
+		}
+		if f, err := toFloat(args[0]); err == nil {
+			return FLOAT(modFunc(f)), nil
+		}
+		if obj, ok := elementwise(args[0], modFunc); ok {
+			return obj, nil
+		}
+		return nil, fmt.Errorf("cannot use %v", args[0].Type())
+	}
+}
+
+// minMax returns a builtin that finds the min or max of a single Map or Set, or of two values
+func minMax(floatOp func(a, b float64) float64, intOp func(a, b int) int, mapOp func(zmath.Map) float64, setOp func(zmath.Set) float64) builtin {
+	single := stat(mapOp, setOp)
+	return func(args []Object) (Object, error) {
+		if len(args) == 1 {
+			return single(args)
+		}
+		if err := argCount(args, 2); err != nil {
+			return nil, err
+		}
+		return combine(args[0], args[1], floatOp, func(a, b int) (int, error) { return intOp(a, b), nil })
+	}
+}
+
+func divInt(a, b int) (int, error) {
+	if b == 0 {
+		return 0, fmt.Errorf("integer division by zero")
+	}
+	return a / b, nil
+}
+
+func modInt(a, b int) (int, error) {
+	if b == 0 {
+		return 0, fmt.Errorf("integer division by zero")
+	}
+	return a % b, nil
+}
+
+//                                       //
+// - - - CONVERSIONS & CONSTRUCTORS - - - //
+//                                       //
+
+func convertArg(args []Object, to DataType) (Object, error) {
+	if err := argCount(args, 1); err != nil {
+		return nil, err
+	}
+	return convert(args[0], to)
+}
+
+func newVec(args []Object) (Object, error) {
+	f, err := floatArgs(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	return VEC{f[0], f[1]}, nil
+}
+
+func newVecInt(args []Object) (Object, error) {
+	f, err := floatArgs(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	return VECINT{int(f[0]), int(f[1])}, nil
+}
+
+// newMap accepts (width, height), (width, height, init), (bounds) or (bounds, init)
+func newMap(args []Object) (Object, error) {
+	if len(args) > 0 {
+		if b, ok := args[0].(VECINT); ok {
+			args = append([]Object{INT(b.X), INT(b.Y)}, args[1:]...)
+		}
+	}
+	if len(args) == 2 {
+		args = append(args, FLOAT(0))
+	}
+	f, err := floatArgs(args, 3)
+	if err != nil {
+		return nil, err
+	}
+	if f[0] < 1 || f[1] < 1 {
+		return nil, fmt.Errorf("Map dimensions must be > 0")
+	}
+	return MAP(zmath.NewMap(zmath.VI(int(f[0]), int(f[1])), f[2])), nil
+}
+
+func newSet(args []Object) (Object, error) {
+	f, err := floatArgs(args, len(args))
+	return SET(f), err
+}
+
+//                               //
+// - - - MAPS AND SETS - - -     //
+//                               //
+
+func setLen(args []Object) (Object, error) {
+	if err := argCount(args, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case SET:
+		return INT(len(v)), nil
+	case STRING:
+		return INT(len(v)), nil
+	}
+	return nil, fmt.Errorf("cannot take length of %v", args[0].Type())
+}
+
+// stat returns a builtin that reduces a single Map or Set to a Float
+func stat(mapOp func(zmath.Map) float64, setOp func(zmath.Set) float64) builtin {
+	return func(args []Object) (Object, error) {
+		if err := argCount(args, 1); err != nil {
+			return nil, err
+		}
+		switch v := args[0].(type) {
+		case MAP:
+			if len(v) == 0 {
+				return nil, fmt.Errorf("empty Map")
+			}
+			return FLOAT(mapOp(zmath.Map(v))), nil
+		case SET:
+			if len(v) == 0 {
+				return nil, fmt.Errorf("empty Set")
+			}
+			return FLOAT(setOp(zmath.Set(v))), nil
+		}
+		return nil, fmt.Errorf("expected a Map or Set, got %v", args[0].Type())
+	}
+}
+
+func setSum(s zmath.Set) float64 {
+	var sum float64
+	for _, val := range s {
+		sum += val
+	}
+	return sum
+}
+
+func mapBounds(args []Object) (Object, error) {
+	m, err := mapArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return VECINT(m.Bounds()), nil
+}
+
+func mapAt(args []Object) (Object, error) {
+	m, err := mapArg(args, 3)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := posArg(m, args[1:])
+	if err != nil {
+		return nil, err
+	}
+	return FLOAT(m.At(pos)), nil
+}
+
+func mapPut(args []Object) (Object, error) {
+	m, err := mapArg(args, 4)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := posArg(m, args[1:3])
+	if err != nil {
+		return nil, err
+	}
+	val, err := toFloat(args[3])
+	if err != nil {
+		return nil, err
+	}
+	m.Set(pos, val)
+	return MAP(m), nil
+}
+
+func mapCopy(args []Object) (Object, error) {
+	m, err := mapArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return MAP(m.CopyAll()), nil
+}
+
+func mapInterpolate(args []Object) (Object, error) {
+	m, err := mapArg(args, 3)
+	if err != nil {
+		return nil, err
+	}
+	f, err := floatArgs(args[1:], 2)
+	if err != nil {
+		return nil, err
+	}
+	return MAP(m.Interpolate(f[0], f[1])), nil
+}
+
+func mapClamp(args []Object) (Object, error) {
+	m, err := mapArg(args, 3)
+	if err != nil {
+		return nil, err
+	}
+	f, err := floatArgs(args[1:], 2)
+	if err != nil {
+		return nil, err
+	}
+	return MAP(m.SetMin(f[0]).SetMax(f[1])), nil
+}
+
+func mapBlur(args []Object) (Object, error) {
+	m, err := mapArg(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	r, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return MAP(m.BlurGaussian(r)), nil
+}
+
+// mapMod returns a builtin wrapping a zmath.Map function that takes no arguments besides the Map
+func mapMod(modFunc func(zmath.Map) zmath.Map) builtin {
+	return func(args []Object) (Object, error) {
+		m, err := mapArg(args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return MAP(modFunc(m)), nil
+	}
+}
+
+func mapLoad(args []Object) (Object, error) {
+	if err := argCount(args, 1); err != nil {
+		return nil, err
+	}
+	path, ok := args[0].(STRING)
+	if !ok {
+		return nil, fmt.Errorf("expected a String path, got %v", args[0].Type())
+	}
+	m, err := zmath.MapFromPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	return MAP(m), nil
+}
+
+// noiseGen returns a builtin that accepts (width, height[, octaves[, boxSize[, seed]]]) and generates noise
+func noiseGen(gen func(noise.Config) zmath.Map) builtin {
+	return func(args []Object) (Object, error) {
+		if len(args) < 2 || len(args) > 5 {
+			return nil, fmt.Errorf("expected width, height[, octaves[, boxSize[, seed]]]")
+		}
+		f, err := floatArgs(args, len(args))
+		if err != nil {
+			return nil, err
+		}
+		if f[0] < 1 || f[1] < 1 {
+			return nil, fmt.Errorf("Map dimensions must be > 0")
+		}
+
+		cfg := noise.DefaultConfig
+		cfg.Dimensions = zmath.VI(int(f[0]), int(f[1]))
+		if len(f) > 2 {
+			cfg.Octaves = int(f[2])
+		}
+		if len(f) > 3 {
+			cfg.BoxSizeInitial = f[3]
+		}
+		if len(f) > 4 {
+			cfg.Seed = int64(f[4])
+		}
+		return MAP(gen(cfg)), nil
+	}
+}
+
+// save saves a Map as a .zmap, or as a .png colored by the named scheme (grayscale by default)
+func save(args []Object) error {
+	m, err := mapArg(args[:1], 1)
+	if err != nil {
+		return err
+	}
+	path, ok := args[1].(STRING)
+	if !ok {
+		return fmt.Errorf("expected a String path, got %v", args[1].Type())
+	}
+
+	if !strings.HasSuffix(strings.ToLower(string(path)), ".png") {
+		if len(args) == 3 {
+			return fmt.Errorf("color schemes can only be used when saving a .png")
+		}
+		return m.Save(string(path))
+	}
+
+	scheme := zimg.SchemeGrayscale
+	if len(args) == 3 {
+		name, ok := args[2].(STRING)
+		if !ok {
+			return fmt.Errorf("expected a String scheme, got %v", args[2].Type())
+		}
+		if scheme, ok = schemes[strings.ToLower(string(name))]; !ok {
+			return fmt.Errorf("unknown color scheme %v", name)
+		}
+	}
+	system.SaveImage(string(path), zimg.Colorify(m, scheme))
+	return nil
+}
+
+//                        //
+// - - - ARGUMENTS - - -  //
+//                        //
+
+func argCount(args []Object, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("expected %v arguments, got %v", n, len(args))
+	}
+	return nil
+}
+
+func floatArgs(args []Object, n int) ([]float64, error) {
+	if err := argCount(args, n); err != nil {
+		return nil, err
+	}
+	f := make([]float64, n)
+	for i, arg := range args {
+		var err error
+		if f[i], err = toFloat(arg); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// mapArg checks the argument count and returns the first argument as a non-empty zmath.Map
+func mapArg(args []Object, n int) (zmath.Map, error) {
+	if err := argCount(args, n); err != nil {
+		return nil, err
+	}
+	m, ok := args[0].(MAP)
+	if !ok {
+		return nil, fmt.Errorf("expected a Map, got %v", args[0].Type())
+	}
+	if len(m) == 0 {
+		return nil, fmt.Errorf("empty Map")
+	}
+	return zmath.Map(m), nil
+}
+
+// posArg turns two numeric arguments into a coordinate inside the passed map
+func posArg(m zmath.Map, args []Object) (zmath.VecInt, error) {
+	f, err := floatArgs(args, 2)
+	if err != nil {
+		return zmath.VecInt{}, err
+	}
+	pos := zmath.VI(int(f[0]), int(f[1]))
+	if !m.ContainsCoord(pos) {
+		return pos, fmt.Errorf("(%v, %v) is out of bounds", pos.X, pos.Y)
+	}
+	return pos, nil
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,498 @@
+package zscript
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Execute runs all of the called Parser's code, stopping at the first error. Any error returned is an *Error
+// carrying the position in the source at which it occurred.
+func (p *Parser) Execute() error {
+	if err := p.Parse(); err != nil {
+		return err
+	}
+
+	p.objects = make(map[string]Object)
+	p.current = nil
+	p.shadowed = nil
+	p.funcs = make(map[string]*funcStmt)
+
+	// register every FUNC first, so that functions may be called before the line that defines them
+	for _, s := range p.program {
+		fn, ok := s.(*funcStmt)
+		if !ok {
+			continue
+		}
+		if _, ok := builtins[fn.name]; ok {
+			return errorf(fn.pos, "cannot redefine builtin %v", fn.name)
+		}
+		if _, ok := p.funcs[fn.name]; ok {
+			return errorf(fn.pos, "%v is already defined", fn.name)
+		}
+		p.funcs[fn.name] = fn
+	}
+
+	p.pushScope()
+	defer p.popScope()
+	_, _, err := p.execBlock(p.program)
+	return err
+}
+
+// execBlock runs a list of statements in order. If a RETURN is reached, its value is returned along with true.
+func (p *Parser) execBlock(block []stmt) (Object, bool, error) {
+	for _, s := range block {
+		ret, returned, err := p.exec(s)
+		if err != nil {
+			return nil, false, atPos(s.position(), err)
+		}
+		if returned {
+			return ret, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (p *Parser) exec(s stmt) (Object, bool, error) {
+	switch s := s.(type) {
+	case *projStmt:
+		p.project = s.name
+
+	case *declStmt:
+		var obj Object = zero(s.typ)
+		if s.value != nil {
+			val, err := p.eval(s.value)
+			if err != nil {
+				return nil, false, err
+			}
+			if obj, err = convert(val, s.typ); err != nil {
+				return nil, false, atPos(s.value.position(), err)
+			}
+		}
+		return nil, false, p.declare(s.name, obj)
+
+	case *assignStmt:
+		val, err := p.eval(s.value)
+		if err != nil {
+			return nil, false, err
+		}
+		return nil, false, p.assignTo(s.target, val)
+
+	case *exprStmt:
+		_, err := p.evalCall(s.call)
+		return nil, false, err
+
+	case *printStmt:
+		args, err := p.evalList(s.args)
+		if err != nil {
+			return nil, false, err
+		}
+		out := make([]string, len(args))
+		for i, arg := range args {
+			out[i] = format(arg)
+		}
+		_, err = fmt.Fprintln(p.out, strings.Join(out, " "))
+		return nil, false, err
+
+	case *saveStmt:
+		args, err := p.evalList(s.args)
+		if err != nil {
+			return nil, false, err
+		}
+		return nil, false, save(args)
+
+	case *forAllStmt:
+		return p.execForAll(s)
+
+	case *funcStmt:
+		// already registered by Execute
+
+	case *returnStmt:
+		ret, err := p.eval(s.value)
+		if err != nil {
+			return nil, false, err
+		}
+		return ret, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// assignTo sets a variable, or an element of a Set or Map column, to a new value
+func (p *Parser) assignTo(target expr, val Object) error {
+	switch t := target.(type) {
+	case *ident:
+		return atPos(t.pos, p.assign(t.name, val))
+
+	case *indexExpr:
+		container, err := p.eval(t.x)
+		if err != nil {
+			return err
+		}
+		i, err := p.evalIndex(t)
+		if err != nil {
+			return err
+		}
+		s, ok := container.(SET)
+		if !ok {
+			return errorf(t.pos, "cannot assign to an element of %v", container.Type())
+		}
+		if i < 0 || i >= len(s) {
+			return errorf(t.index.position(), "index %v out of range [0, %v)", i, len(s))
+		}
+		f, err := toFloat(val)
+		if err != nil {
+			return atPos(t.pos, err)
+		}
+		s[i] = f
+		return nil
+	}
+
+	return errorf(target.position(), "cannot assign to this expression")
+}
+
+// execForAll runs the body once per cell of the map, with the value variable set to the cell's value (and the
+// x, y variables to its coordinates, if present). Whatever the value variable holds afterwards is written back.
+func (p *Parser) execForAll(s *forAllStmt) (Object, bool, error) {
+	obj, err := p.eval(s.m)
+	if err != nil {
+		return nil, false, err
+	}
+	m, ok := obj.(MAP)
+	if !ok {
+		return nil, false, errorf(s.m.position(), "%v needs a Map, got %v", FORALL, obj.Type())
+	}
+
+	p.pushScope()
+	defer p.popScope()
+	for i, name := range s.vars {
+		var init Object = INT(0)
+		if i == 0 {
+			init = FLOAT(0)
+		}
+		if err := p.declare(name, init); err != nil {
+			return nil, false, err
+		}
+	}
+
+	value := s.vars[0]
+	for x, row := range m {
+		for y := range row {
+			p.objects[value] = FLOAT(row[y])
+			if len(s.vars) == 3 {
+				p.objects[s.vars[1]] = INT(x)
+				p.objects[s.vars[2]] = INT(y)
+			}
+
+			p.pushScope()
+			ret, returned, err := p.execBlock(s.body)
+			p.popScope()
+			if err != nil || returned {
+				return ret, returned, err
+			}
+
+			if row[y], err = toFloat(p.objects[value]); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+
+	return nil, false, nil
+}
+
+//                         //
+// - - - EVALUATION - - -  //
+//                         //
+
+func (p *Parser) eval(e expr) (Object, error) {
+	switch e := e.(type) {
+	case *literal:
+		return e.value, nil
+
+	case *ident:
+		if obj, ok := p.lookup(e.name); ok {
+			return obj, nil
+		}
+		if p.isCallable(e.name) {
+			return nil, errorf(e.pos, "%v is a function; call it with %v(...)", e.name, e.name)
+		}
+		return nil, errorf(e.pos, "undefined: %v", e.name)
+
+	case *unaryExpr:
+		x, err := p.eval(e.x)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := unaryOp(e.op, x)
+		return obj, atPos(e.pos, err)
+
+	case *binaryExpr:
+		return p.evalBinary(e)
+
+	case *callExpr:
+		obj, err := p.evalCall(e)
+		if err == nil && obj == nil {
+			return nil, errorf(e.pos, "%v does not return a value", e.name)
+		}
+		return obj, err
+
+	case *indexExpr:
+		container, err := p.eval(e.x)
+		if err != nil {
+			return nil, err
+		}
+		i, err := p.evalIndex(e)
+		if err != nil {
+			return nil, err
+		}
+		return index(container, i, e)
+
+	case *vecExpr:
+		x, err := p.eval(e.x)
+		if err != nil {
+			return nil, err
+		}
+		y, err := p.eval(e.y)
+		if err != nil {
+			return nil, err
+		}
+		xi, xInt := x.(INT)
+		yi, yInt := y.(INT)
+		if xInt && yInt {
+			return VECINT{int(xi), int(yi)}, nil
+		}
+		v, err := newVec([]Object{x, y})
+		return v, atPos(e.pos, err)
+	}
+
+	return nil, errorf(e.position(), "cannot evaluate expression")
+}
+
+// evalCall evaluates a function call. Unlike eval, it returns a nil Object if the function returned nothing.
+func (p *Parser) evalCall(e *callExpr) (Object, error) {
+	args, err := p.evalList(e.args)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := p.call(e.name, args)
+	return obj, atPos(e.pos, err)
+}
+
+func (p *Parser) evalList(list []expr) ([]Object, error) {
+	objs := make([]Object, len(list))
+	for i, e := range list {
+		obj, err := p.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		objs[i] = obj
+	}
+	return objs, nil
+}
+
+func (p *Parser) evalIndex(e *indexExpr) (int, error) {
+	obj, err := p.eval(e.index)
+	if err != nil {
+		return 0, err
+	}
+	i, ok := obj.(INT)
+	if !ok {
+		return 0, errorf(e.index.position(), "index must be an Int, got %v", obj.Type())
+	}
+	return int(i), nil
+}
+
+// index returns an element of a container. Indexing a Map returns one of its columns as a Set that shares the
+// Map's memory, so that m[x][y] = v modifies m.
+func index(container Object, i int, e *indexExpr) (Object, error) {
+	var length int
+	switch c := container.(type) {
+	case MAP:
+		length = len(c)
+	case SET:
+		length = len(c)
+	case VEC, VECINT:
+		length = 2
+	default:
+		return nil, errorf(e.pos, "cannot index %v", container.Type())
+	}
+	if i < 0 || i >= length {
+		return nil, errorf(e.index.position(), "index %v out of range [0, %v)", i, length)
+	}
+
+	switch c := container.(type) {
+	case MAP:
+		return SET(c[i]), nil
+	case SET:
+		return FLOAT(c[i]), nil
+	case VEC:
+		return FLOAT([2]float64{c.X, c.Y}[i]), nil
+	case VECINT:
+		return INT([2]int{c.X, c.Y}[i]), nil
+	}
+	return nil, nil
+}
+
+func (p *Parser) evalBinary(e *binaryExpr) (Object, error) {
+	x, err := p.eval(e.x)
+	if err != nil {
+		return nil, err
+	}
+
+	// && and || short-circuit
+	if e.op == tokAnd || e.op == tokOr {
+		xb, ok := x.(BOOL)
+		if !ok {
+			return nil, errorf(e.x.position(), "%v needs Bools, got %v", e.op, x.Type())
+		}
+		if bool(xb) == (e.op == tokOr) {
+			return xb, nil
+		}
+		y, err := p.eval(e.y)
+		if err != nil {
+			return nil, err
+		}
+		yb, ok := y.(BOOL)
+		if !ok {
+			return nil, errorf(e.y.position(), "%v needs Bools, got %v", e.op, y.Type())
+		}
+		return yb, nil
+	}
+
+	y, err := p.eval(e.y)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := binaryOp(e.op, x, y)
+	return obj, atPos(e.pos, err)
+}
+
+// arithmeticOps contains the float and int implementations of each arithmetic operator
+var arithmeticOps = map[tokenKind]struct {
+	floatOp func(a, b float64) float64
+	intOp   func(a, b int) (int, error)
+}{
+	tokPlus:  {func(a, b float64) float64 { return a + b }, func(a, b int) (int, error) { return a + b, nil }},
+	tokMinus: {func(a, b float64) float64 { return a - b }, func(a, b int) (int, error) { return a - b, nil }},
+	tokStar:  {func(a, b float64) float64 { return a * b }, func(a, b int) (int, error) { return a * b, nil }},
+	tokSlash: {func(a, b float64) float64 { return a / b }, divInt},
+	tokMod:   {math.Mod, modInt},
+	tokCaret: {math.Pow, nil},
+}
+
+func binaryOp(op tokenKind, x, y Object) (Object, error) {
+	if ops, ok := arithmeticOps[op]; ok {
+		if op == tokPlus {
+			_, xStr := x.(STRING)
+			_, yStr := y.(STRING)
+			if xStr || yStr {
+				return STRING(format(x) + format(y)), nil
+			}
+		}
+		return combine(x, y, ops.floatOp, ops.intOp)
+	}
+
+	switch op {
+	case tokEq, tokNeq:
+		eq, err := equal(x, y)
+		return BOOL(eq == (op == tokEq)), err
+	}
+
+	xf, err := toFloat(x)
+	if err != nil {
+		return nil, err
+	}
+	yf, err := toFloat(y)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case tokLess:
+		return BOOL(xf < yf), nil
+	case tokLeq:
+		return BOOL(xf <= yf), nil
+	case tokGreat:
+		return BOOL(xf > yf), nil
+	case tokGeq:
+		return BOOL(xf >= yf), nil
+	}
+	return nil, fmt.Errorf("unknown operator %v", op)
+}
+
+func unaryOp(op tokenKind, x Object) (Object, error) {
+	if op == tokNot {
+		b, ok := x.(BOOL)
+		if !ok {
+			return nil, fmt.Errorf("! needs a Bool, got %v", x.Type())
+		}
+		return !b, nil
+	}
+
+	switch v := x.(type) {
+	case INT:
+		return -v, nil
+	case FLOAT:
+		return -v, nil
+	case VECINT:
+		return VECINT{-v.X, -v.Y}, nil
+	}
+	if obj, ok := elementwise(x, func(f float64) float64 { return -f }); ok {
+		return obj, nil
+	}
+	return nil, fmt.Errorf("cannot negate %v", x.Type())
+}
+
+// equal compares two numbers, Bools, Strings, Vecs or VecInts
+func equal(x, y Object) (bool, error) {
+	if xf, err := toFloat(x); err == nil {
+		yf, err := toFloat(y)
+		return xf == yf, err
+	}
+	if x.Type() != y.Type() {
+		return false, fmt.Errorf("cannot compare %v and %v", x.Type(), y.Type())
+	}
+	switch x.(type) {
+	case STRING, VEC, VECINT:
+		return x == y, nil
+	}
+	return false, fmt.Errorf("cannot compare %v", x.Type())
+}
+
+// isCallable returns whether a name refers to a builtin or user-defined function
+func (p *Parser) isCallable(name string) bool {
+	if _, ok := builtins[name]; ok {
+		return true
+	}
+	_, ok := p.funcs[name]
+	return ok
+}
+
+// call calls the desired builtin or user-defined function with already-evaluated arguments
+func (p *Parser) call(name string, args []Object) (Object, error) {
+	if fn, ok := builtins[name]; ok {
+		obj, err := fn(args)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", name, err)
+		}
+		return obj, nil
+	}
+
+	fn, ok := p.funcs[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined function %v", name)
+	}
+	if len(args) != len(fn.params) {
+		return nil, fmt.Errorf("%v takes %v arguments, got %v", name, len(fn.params), len(args))
+	}
+
+	p.pushScope()
+	defer p.popScope()
+	for i, param := range fn.params {
+		if err := p.declare(param, args[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	ret, _, err := p.execBlock(fn.body)
+	return ret, err
+}
@@ -15,16 +15,31 @@ const (
 	SAVE    Keyword = "SAVE"
 	PRINT   Keyword = "PRINT"
 	FUNC    Keyword = "FUNC"
+	RETURN  Keyword = "RETURN"
+	END     Keyword = "END"
 )
 
 // Datatypes
 const (
-	Int   DataType = "Int"
-	Float DataType = "Float"
-	Bool  DataType = "Bool"
+	Int    DataType = "Int"
+	Float  DataType = "Float"
+	Bool   DataType = "Bool"
+	String DataType = "String"
 
 	Set    DataType = "Set"
 	Map    DataType = "Map"
 	Vec    DataType = "Vec"
 	VecInt DataType = "VecInt"
 )
+
+// dataTypes lists every DataType that may begin a declaration
+var dataTypes = map[string]DataType{
+	string(Int):    Int,
+	string(Float):  Float,
+	string(Bool):   Bool,
+	string(String): String,
+	string(Set):    Set,
+	string(Map):    Map,
+	string(Vec):    Vec,
+	string(VecInt): VecInt,
+}
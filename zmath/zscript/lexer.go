@@ -0,0 +1,315 @@
+package zscript
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Pos is a position within a zscript source file. Both Line and Col start at 1.
+type Pos struct {
+	Line, Col int
+}
+
+// String returns the position formatted as line:col
+func (p Pos) String() string {
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Col)
+}
+
+// Error is an error that occurred at a specific position in a zscript source file
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// errorf returns a new *Error at the desired position
+func errorf(pos Pos, format string, args ...interface{}) *Error {
+	return &Error{
+		Pos: pos,
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+// atPos attaches a position to an error, unless it already has a more precise one
+func atPos(pos Pos, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	return &Error{
+		Pos: pos,
+		Msg: err.Error(),
+	}
+}
+
+type tokenKind int
+
+// Token kinds
+const (
+	tokEOF tokenKind = iota
+	tokNewline
+	tokIdent
+	tokInt
+	tokFloat
+	tokString
+
+	tokAssign // =
+	tokPlus   // +
+	tokMinus  // -
+	tokStar   // *
+	tokSlash  // /
+	tokMod    // %
+	tokCaret  // ^
+	tokEq     // ==
+	tokNeq    // !=
+	tokLess   // <
+	tokLeq    // <=
+	tokGreat  // >
+	tokGeq    // >=
+	tokAnd    // &&
+	tokOr     // ||
+	tokNot    // !
+	tokLParen // (
+	tokRParen // )
+	tokLBrack // [
+	tokRBrack // ]
+	tokComma  // ,
+)
+
+var tokenNames = map[tokenKind]string{
+	tokEOF:     "end of file",
+	tokNewline: "end of line",
+	tokIdent:   "name",
+	tokInt:     "integer",
+	tokFloat:   "number",
+	tokString:  "string",
+}
+
+// operators maps every operator's text to its token kind. Two-character operators are matched first.
+var operators = map[string]tokenKind{
+	"=":  tokAssign,
+	"+":  tokPlus,
+	"-":  tokMinus,
+	"*":  tokStar,
+	"/":  tokSlash,
+	"%":  tokMod,
+	"^":  tokCaret,
+	"==": tokEq,
+	"!=": tokNeq,
+	"<":  tokLess,
+	"<=": tokLeq,
+	">":  tokGreat,
+	">=": tokGeq,
+	"&&": tokAnd,
+	"||": tokOr,
+	"!":  tokNot,
+	"(":  tokLParen,
+	")":  tokRParen,
+	"[":  tokLBrack,
+	"]":  tokRBrack,
+	",":  tokComma,
+}
+
+func (k tokenKind) String() string {
+	if name, ok := tokenNames[k]; ok {
+		return name
+	}
+	for text, kind := range operators {
+		if kind == k {
+			return "'" + text + "'"
+		}
+	}
+	return "unknown token"
+}
+
+// token is a single lexical unit of zscript source code
+type token struct {
+	kind tokenKind
+	text string
+	pos  Pos
+}
+
+// lexer breaks zscript source code up into tokens. Newlines are significant, since they end statements,
+// except inside parentheses and brackets, so that long calls may be split across several lines.
+type lexer struct {
+	src   []rune
+	idx   int
+	pos   Pos
+	depth int // how many ( and [ are currently open
+}
+
+// lex returns all of the tokens in a zscript source, ending with an end of file token
+func lex(src string) ([]token, error) {
+	lx := &lexer{
+		src: []rune(src),
+		pos: Pos{1, 1},
+	}
+
+	tokens := make([]token, 0)
+	for {
+		tok, err := lx.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.kind == tokEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (lx *lexer) peek(ahead int) rune {
+	if lx.idx+ahead >= len(lx.src) {
+		return 0
+	}
+	return lx.src[lx.idx+ahead]
+}
+
+func (lx *lexer) advance() rune {
+	r := lx.src[lx.idx]
+	lx.idx++
+	if r == '\n' {
+		lx.pos.Line++
+		lx.pos.Col = 1
+	} else {
+		lx.pos.Col++
+	}
+	return r
+}
+
+func (lx *lexer) next() (token, error) {
+	// skip whitespace and comments, stopping at significant newlines
+	for lx.idx < len(lx.src) {
+		r := lx.peek(0)
+		if r == '#' {
+			for lx.idx < len(lx.src) && lx.peek(0) != '\n' {
+				lx.advance()
+			}
+		} else if r == ' ' || r == '\t' || r == '\r' || (r == '\n' && lx.depth > 0) {
+			lx.advance()
+		} else {
+			break
+		}
+	}
+
+	start := lx.pos
+	if lx.idx >= len(lx.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	r := lx.peek(0)
+	switch {
+	case r == '\n':
+		lx.advance()
+		return token{kind: tokNewline, text: "\n", pos: start}, nil
+
+	case isLetter(r):
+		begin := lx.idx
+		for isLetter(lx.peek(0)) || isDigit(lx.peek(0)) {
+			lx.advance()
+		}
+		return token{kind: tokIdent, text: string(lx.src[begin:lx.idx]), pos: start}, nil
+
+	case isDigit(r) || (r == '.' && isDigit(lx.peek(1))):
+		return lx.number(start)
+
+	case r == '"':
+		return lx.string(start)
+	}
+
+	if kind, ok := operators[string([]rune{r, lx.peek(1)})]; ok {
+		lx.advance()
+		lx.advance()
+		return token{kind: kind, text: string([]rune{r, lx.src[lx.idx-1]}), pos: start}, nil
+	}
+	if kind, ok := operators[string(r)]; ok {
+		lx.advance()
+		switch kind {
+		case tokLParen, tokLBrack:
+			lx.depth++
+		case tokRParen, tokRBrack:
+			if lx.depth > 0 {
+				lx.depth--
+			}
+		}
+		return token{kind: kind, text: string(r), pos: start}, nil
+	}
+
+	return token{}, errorf(start, "unexpected character %q", r)
+}
+
+// number lexes an integer or floating point literal, such as 12, 0.5, .5 or 1e-3
+func (lx *lexer) number(start Pos) (token, error) {
+	var (
+		begin   = lx.idx
+		isFloat = false
+	)
+	for isDigit(lx.peek(0)) {
+		lx.advance()
+	}
+	if lx.peek(0) == '.' {
+		isFloat = true
+		lx.advance()
+		for isDigit(lx.peek(0)) {
+			lx.advance()
+		}
+	}
+	if e := lx.peek(0); e == 'e' || e == 'E' {
+		sign := lx.peek(1) == '+' || lx.peek(1) == '-'
+		if isDigit(lx.peek(1)) || (sign && isDigit(lx.peek(2))) {
+			isFloat = true
+			lx.advance()
+			if sign {
+				lx.advance()
+			}
+			for isDigit(lx.peek(0)) {
+				lx.advance()
+			}
+		}
+	}
+	if isLetter(lx.peek(0)) {
+		return token{}, errorf(lx.pos, "unexpected %q in number", lx.peek(0))
+	}
+
+	tok := token{kind: tokInt, text: string(lx.src[begin:lx.idx]), pos: start}
+	if isFloat {
+		tok.kind = tokFloat
+	}
+	return tok, nil
+}
+
+// string lexes a double-quoted string literal, which may contain Go-style escapes such as \" and \n
+func (lx *lexer) string(start Pos) (token, error) {
+	begin := lx.idx
+	lx.advance() // opening quote
+	for {
+		if lx.idx >= len(lx.src) || lx.peek(0) == '\n' {
+			return token{}, errorf(start, "string literal not terminated")
+		}
+		r := lx.advance()
+		if r == '\\' && lx.idx < len(lx.src) {
+			lx.advance()
+		} else if r == '"' {
+			break
+		}
+	}
+
+	text, err := strconv.Unquote(string(lx.src[begin:lx.idx]))
+	if err != nil {
+		return token{}, errorf(start, "invalid string literal")
+	}
+	return token{kind: tokString, text: text, pos: start}, nil
+}
+
+func isLetter(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
@@ -1,5 +1,31 @@
 package main
 
+import (
+	"fmt"
+	"os"
+
+	"github.com/Isarcus/zarks/zmath/zscript"
+)
+
+const usage = "usage: zscript run file.zs"
+
 func main() {
+	if len(os.Args) != 3 || os.Args[1] != "run" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
 
+	p, err := zscript.NewParserFromPath(os.Args[2])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := p.Execute(); err != nil {
+		if zerr, ok := err.(*zscript.Error); ok {
+			fmt.Fprintf(os.Stderr, "%v:%v: %v\n", os.Args[2], zerr.Pos, zerr.Msg)
+		} else {
+			fmt.Fprintln(os.Stderr, os.Args[2]+":", err)
+		}
+		os.Exit(1)
+	}
 }
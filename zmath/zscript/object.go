@@ -1,6 +1,11 @@
 package zscript
 
-import "github.com/Isarcus/zarks/zmath"
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Isarcus/zarks/zmath"
+)
 
 // Object is the interface type of all zscript variables, somewhat a la Python
 type Object interface {
@@ -15,6 +20,8 @@ type (
 	FLOAT float64
 	// BOOL represents a boolean
 	BOOL bool
+	// STRING represents a string
+	STRING string
 )
 
 // zmath types
@@ -44,6 +51,11 @@ func (b BOOL) Type() DataType {
 	return Bool
 }
 
+// Type returns String
+func (s STRING) Type() DataType {
+	return String
+}
+
 // Type returns Set
 func (s SET) Type() DataType {
 	return Set
@@ -63,3 +75,104 @@ func (v VEC) Type() DataType {
 func (v VECINT) Type() DataType {
 	return VecInt
 }
+
+// toFloat converts any numeric Object to a float64
+func toFloat(o Object) (float64, error) {
+	switch v := o.(type) {
+	case INT:
+		return float64(v), nil
+	case FLOAT:
+		return float64(v), nil
+	case BOOL:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("expected a number, got %v", o.Type())
+}
+
+// toInt converts any numeric Object to an int, truncating floats
+func toInt(o Object) (int, error) {
+	f, err := toFloat(o)
+	return int(f), err
+}
+
+// convert returns the passed Object as the desired DataType, or an error if no sensible conversion exists
+func convert(o Object, to DataType) (Object, error) {
+	if o.Type() == to {
+		return o, nil
+	}
+
+	switch to {
+	case Int:
+		i, err := toInt(o)
+		return INT(i), err
+	case Float:
+		f, err := toFloat(o)
+		return FLOAT(f), err
+	case Bool:
+		f, err := toFloat(o)
+		return BOOL(f != 0), err
+	case Vec:
+		if vi, ok := o.(VECINT); ok {
+			return VEC(zmath.VecInt(vi).V()), nil
+		}
+	case VecInt:
+		if v, ok := o.(VEC); ok {
+			return VECINT(zmath.Vec(v).VI()), nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot use %v as %v", o.Type(), to)
+}
+
+// zero returns the zero value of a DataType
+func zero(t DataType) Object {
+	switch t {
+	case Int:
+		return INT(0)
+	case Float:
+		return FLOAT(0)
+	case Bool:
+		return BOOL(false)
+	case String:
+		return STRING("")
+	case Set:
+		return SET{}
+	case Map:
+		return MAP{}
+	case Vec:
+		return VEC{}
+	case VecInt:
+		return VECINT{}
+	}
+	return nil
+}
+
+// format returns a human-readable representation of an Object
+func format(o Object) string {
+	switch v := o.(type) {
+	case INT:
+		return strconv.Itoa(int(v))
+	case FLOAT:
+		return strconv.FormatFloat(float64(v), 'g', -1, 64)
+	case BOOL:
+		return strconv.FormatBool(bool(v))
+	case STRING:
+		return string(v)
+	case SET:
+		return fmt.Sprint([]float64(v))
+	case MAP:
+		if len(v) == 0 {
+			return "Map[0x0]"
+		}
+		b := zmath.Map(v).Bounds()
+		return "Map[" + strconv.Itoa(b.X) + "x" + strconv.Itoa(b.Y) + "]"
+	case VEC:
+		return fmt.Sprintf("(%v, %v)", v.X, v.Y)
+	case VECINT:
+		return fmt.Sprintf("(%v, %v)", v.X, v.Y)
+	}
+	return "<nil>"
+}
@@ -1,38 +1,560 @@
 package zscript
 
-import "os"
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
 
 // Parser is a struct capable of loading and executing some zscript code
 type Parser struct {
-	lines [][]string
+	src     string
+	program []stmt
 
-	objects map[string]Object
-	current [][]string // the last []string of current is the list of all variables in the narrowest scope
+	objects  map[string]Object
+	current  [][]string          // the last []string of current is the list of all variables in the narrowest scope
+	shadowed []map[string]Object // outer variables hidden by each scope, restored when that scope is popped
+	funcs    map[string]*funcStmt
+
+	project string
+	out     io.Writer
 }
 
 // NewParser returns a new Parser from a file, but does not execute the code it loads in
 func NewParser(f os.File) *Parser {
-	var (
-		linesRaw = GetLines(f)
-		lines    = make([][]string, len(linesRaw))
-	)
-	for i, line := range linesRaw {
-		lines[i] = GetWords(line)
+	src, _ := ioutil.ReadAll(&f)
+	return NewParserFromString(string(src))
+}
+
+// NewParserFromPath opens the file at the desired path and returns a new Parser for it
+func NewParserFromPath(path string) (*Parser, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
+	return NewParserFromString(string(src)), nil
+}
 
+// NewParserFromString returns a new Parser for the passed source code, but does not execute it
+func NewParserFromString(src string) *Parser {
 	return &Parser{
-		lines: lines,
+		src: src,
+		out: os.Stdout,
+	}
+}
+
+// SetOutput changes where PRINT statements write to. By default, this is os.Stdout.
+func (p *Parser) SetOutput(w io.Writer) *Parser {
+	p.out = w
+	return p
+}
+
+// Project returns the name given to the script by its PROJ statement, if it had one
+func (p *Parser) Project() string {
+	return p.project
+}
+
+// Parse checks the called Parser's code for syntax errors without executing it. Any error returned is an *Error.
+func (p *Parser) Parse() error {
+	if p.program != nil {
+		return nil
+	}
+
+	toks, err := lex(p.src)
+	if err != nil {
+		return err
+	}
+
+	sx := &syntax{toks: toks}
+	program, err := sx.block(false)
+	if err != nil {
+		return err
+	}
+	p.program = program
+	return nil
+}
+
+//                     //
+// - - - SYNTAX - - -  //
+//                     //
+
+// syntax turns a list of tokens into statements with a recursive descent parser
+type syntax struct {
+	toks   []token
+	i      int
+	depth  int  // how many FORALL and FUNC blocks are currently open
+	inFunc bool // whether a FUNC block is currently open
+}
+
+// binary operator precedence; higher binds tighter. ^ is handled separately since it is right-associative.
+var precedence = map[tokenKind]int{
+	tokOr:    1,
+	tokAnd:   2,
+	tokEq:    3,
+	tokNeq:   3,
+	tokLess:  3,
+	tokLeq:   3,
+	tokGreat: 3,
+	tokGeq:   3,
+	tokPlus:  4,
+	tokMinus: 4,
+	tokStar:  5,
+	tokSlash: 5,
+	tokMod:   5,
+}
+
+func (sx *syntax) peek() token {
+	return sx.toks[sx.i]
+}
+
+func (sx *syntax) next() token {
+	tok := sx.toks[sx.i]
+	if tok.kind != tokEOF {
+		sx.i++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is of the desired kind
+func (sx *syntax) accept(kind tokenKind) bool {
+	if sx.peek().kind == kind {
+		sx.next()
+		return true
+	}
+	return false
+}
+
+func (sx *syntax) expect(kind tokenKind) (token, error) {
+	tok := sx.next()
+	if tok.kind != kind {
+		return tok, unexpected(tok, kind.String())
+	}
+	return tok, nil
+}
+
+// endLine expects the end of a statement
+func (sx *syntax) endLine() error {
+	tok := sx.peek()
+	if tok.kind != tokNewline && tok.kind != tokEOF {
+		return unexpected(tok, "end of line")
+	}
+	sx.next()
+	return nil
+}
+
+// name expects an identifier that can be used as a variable or function name
+func (sx *syntax) name() (token, error) {
+	tok, err := sx.expect(tokIdent)
+	if err != nil {
+		return tok, err
+	}
+	if !isIdentifier(tok.text) {
+		return tok, errorf(tok.pos, "%v cannot be used as a name", tok.text)
+	}
+	return tok, nil
+}
+
+// block parses statements until the end of the file or, if inBlock is true, until the matching END
+func (sx *syntax) block(inBlock bool) ([]stmt, error) {
+	block := make([]stmt, 0)
+	for {
+		tok := sx.peek()
+		switch {
+		case tok.kind == tokNewline:
+			sx.next()
+			continue
+		case tok.kind == tokEOF:
+			if inBlock {
+				return nil, errorf(tok.pos, "unexpected end of file, expected %v", END)
+			}
+			return block, nil
+		case tok.kind == tokIdent && Keyword(tok.text) == END:
+			if !inBlock {
+				return nil, errorf(tok.pos, "%v without matching %v or %v", END, FORALL, FUNC)
+			}
+			sx.next()
+			return block, sx.endLine()
+		}
+
+		s, err := sx.statement()
+		if err != nil {
+			return nil, err
+		}
+		block = append(block, s)
+	}
+}
+
+func (sx *syntax) statement() (stmt, error) {
+	tok := sx.peek()
+	if tok.kind == tokIdent {
+		switch Keyword(tok.text) {
+		case PACKAGE:
+			sx.next()
+			name, err := sx.expect(tokIdent)
+			if err != nil {
+				return nil, err
+			}
+			return &projStmt{pos: tok.pos, name: name.text}, sx.endLine()
+
+		case PRINT:
+			sx.next()
+			args, err := sx.list()
+			if err != nil {
+				return nil, err
+			}
+			return &printStmt{pos: tok.pos, args: args}, sx.endLine()
+
+		case SAVE:
+			sx.next()
+			args, err := sx.list()
+			if err != nil {
+				return nil, err
+			}
+			if len(args) < 2 || len(args) > 3 {
+				return nil, errorf(tok.pos, "usage: %v map, \"path\"[, \"scheme\"]", SAVE)
+			}
+			return &saveStmt{pos: tok.pos, args: args}, sx.endLine()
+
+		case FORALL:
+			return sx.forAll()
+
+		case FUNC:
+			return sx.function()
+
+		case RETURN:
+			sx.next()
+			if !sx.inFunc {
+				return nil, errorf(tok.pos, "%v outside of %v", RETURN, FUNC)
+			}
+			value, err := sx.expr()
+			if err != nil {
+				return nil, err
+			}
+			return &returnStmt{pos: tok.pos, value: value}, sx.endLine()
+		}
+
+		if t, ok := dataTypes[tok.text]; ok {
+			return sx.declaration(t)
+		}
+	}
+
+	e, err := sx.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	if eq := sx.peek(); eq.kind == tokAssign {
+		sx.next()
+		switch e.(type) {
+		case *ident, *indexExpr:
+		default:
+			return nil, errorf(e.position(), "cannot assign to this expression")
+		}
+		value, err := sx.expr()
+		if err != nil {
+			return nil, err
+		}
+		return &assignStmt{pos: eq.pos, target: e, value: value}, sx.endLine()
+	}
+
+	call, ok := e.(*callExpr)
+	if !ok {
+		return nil, errorf(e.position(), "expression is not used")
+	}
+	return &exprStmt{call: call}, sx.endLine()
+}
+
+// declaration parses `Type name` or `Type name = value`
+func (sx *syntax) declaration(t DataType) (stmt, error) {
+	tok := sx.next()
+	name, err := sx.name()
+	if err != nil {
+		return nil, err
+	}
+
+	decl := &declStmt{pos: tok.pos, typ: t, name: name.text}
+	if sx.accept(tokAssign) {
+		if decl.value, err = sx.expr(); err != nil {
+			return nil, err
+		}
+	}
+	return decl, sx.endLine()
+}
+
+// forAll parses `FORALL map value` or `FORALL map value x y`, followed by a block
+func (sx *syntax) forAll() (stmt, error) {
+	tok := sx.next()
+	m, err := sx.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make([]string, 0, 3)
+	for sx.peek().kind == tokIdent {
+		name, err := sx.name()
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars, name.text)
+	}
+	if len(vars) != 1 && len(vars) != 3 {
+		return nil, errorf(tok.pos, "usage: %v map value [x y]", FORALL)
 	}
+	if err := sx.endLine(); err != nil {
+		return nil, err
+	}
+
+	sx.depth++
+	body, err := sx.block(true)
+	sx.depth--
+	if err != nil {
+		return nil, err
+	}
+	return &forAllStmt{pos: tok.pos, m: m, vars: vars, body: body}, nil
 }
 
-// Execute runs all of the called Parser's code
-/*
-func (p *Parser) Execute() {
-	for _, line := range p.lines {
-		for i, word := range line {
-			if i == 0 {
+// function parses `FUNC name(params...)`, followed by a block
+func (sx *syntax) function() (stmt, error) {
+	tok := sx.next()
+	if sx.depth > 0 {
+		return nil, errorf(tok.pos, "%v may only be defined at the top level", FUNC)
+	}
+	name, err := sx.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sx.expect(tokLParen); err != nil {
+		return nil, err
+	}
 
+	params := make([]string, 0)
+	for !sx.accept(tokRParen) {
+		if len(params) > 0 {
+			if _, err := sx.expect(tokComma); err != nil {
+				return nil, err
 			}
 		}
+		param, err := sx.name()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param.text)
+	}
+	if err := sx.endLine(); err != nil {
+		return nil, err
+	}
+
+	sx.depth++
+	sx.inFunc = true
+	body, err := sx.block(true)
+	sx.depth--
+	sx.inFunc = false
+	if err != nil {
+		return nil, err
+	}
+	return &funcStmt{pos: tok.pos, name: name.text, params: params, body: body}, nil
+}
+
+// list parses zero or more comma-separated expressions, up to the end of the line
+func (sx *syntax) list() ([]expr, error) {
+	list := make([]expr, 0)
+	if k := sx.peek().kind; k == tokNewline || k == tokEOF {
+		return list, nil
+	}
+	for {
+		e, err := sx.expr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+		if !sx.accept(tokComma) {
+			return list, nil
+		}
+	}
+}
+
+//                          //
+// - - - EXPRESSIONS - - -  //
+//                          //
+
+func (sx *syntax) expr() (expr, error) {
+	return sx.binary(1)
+}
+
+// binary parses binary operators of at least the desired precedence, by precedence climbing
+func (sx *syntax) binary(minPrec int) (expr, error) {
+	x, err := sx.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := sx.peek()
+		prec, ok := precedence[op.kind]
+		if !ok || prec < minPrec {
+			return x, nil
+		}
+		sx.next()
+		y, err := sx.binary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryExpr{pos: op.pos, op: op.kind, x: x, y: y}
 	}
-}*/
+}
+
+func (sx *syntax) unary() (expr, error) {
+	if op := sx.peek(); op.kind == tokMinus || op.kind == tokNot {
+		sx.next()
+		x, err := sx.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{pos: op.pos, op: op.kind, x: x}, nil
+	}
+	return sx.power()
+}
+
+// power parses x ^ y, which binds tighter than unary minus (so -2^2 is -4) and is right-associative
+func (sx *syntax) power() (expr, error) {
+	x, err := sx.postfix()
+	if err != nil {
+		return nil, err
+	}
+	if op := sx.peek(); op.kind == tokCaret {
+		sx.next()
+		y, err := sx.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryExpr{pos: op.pos, op: tokCaret, x: x, y: y}, nil
+	}
+	return x, nil
+}
+
+// postfix parses element access, such as s[i] or m[x][y]
+func (sx *syntax) postfix() (expr, error) {
+	x, err := sx.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		open := sx.peek()
+		if open.kind != tokLBrack {
+			return x, nil
+		}
+		sx.next()
+		index, err := sx.expr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := sx.expect(tokRBrack); err != nil {
+			return nil, err
+		}
+		x = &indexExpr{pos: open.pos, x: x, index: index}
+	}
+}
+
+func (sx *syntax) primary() (expr, error) {
+	tok := sx.next()
+	switch tok.kind {
+	case tokInt:
+		i, err := strconv.Atoi(tok.text)
+		if err != nil {
+			return nil, errorf(tok.pos, "integer %v out of range", tok.text)
+		}
+		return &literal{pos: tok.pos, value: INT(i)}, nil
+
+	case tokFloat:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, errorf(tok.pos, "invalid number %v", tok.text)
+		}
+		return &literal{pos: tok.pos, value: FLOAT(f)}, nil
+
+	case tokString:
+		return &literal{pos: tok.pos, value: STRING(tok.text)}, nil
+
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literal{pos: tok.pos, value: BOOL(true)}, nil
+		case "false":
+			return &literal{pos: tok.pos, value: BOOL(false)}, nil
+		}
+		if sx.peek().kind == tokLParen {
+			return sx.call(tok)
+		}
+		return &ident{pos: tok.pos, name: tok.text}, nil
+
+	case tokLParen:
+		x, err := sx.expr()
+		if err != nil {
+			return nil, err
+		}
+		if sx.accept(tokComma) {
+			y, err := sx.expr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := sx.expect(tokRParen); err != nil {
+				return nil, err
+			}
+			return &vecExpr{pos: tok.pos, x: x, y: y}, nil
+		}
+		if _, err := sx.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+
+	return nil, unexpected(tok, "expression")
+}
+
+// call parses the argument list of name(args...)
+func (sx *syntax) call(name token) (expr, error) {
+	sx.next() // (
+	args := make([]expr, 0)
+	for !sx.accept(tokRParen) {
+		if len(args) > 0 {
+			if _, err := sx.expect(tokComma); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := sx.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return &callExpr{pos: name.pos, name: name.text, args: args}, nil
+}
+
+// unexpected returns an error describing an unexpected token
+func unexpected(tok token, wanted string) *Error {
+	found := tok.kind.String()
+	if tok.kind == tokIdent || tok.kind == tokInt || tok.kind == tokFloat {
+		found = tok.text
+	} else if tok.kind == tokString {
+		found = strconv.Quote(tok.text)
+	}
+	return errorf(tok.pos, "unexpected %v, expected %v", found, wanted)
+}
+
+// isIdentifier returns whether a word may be used as a variable or function name
+func isIdentifier(word string) bool {
+	if len(word) == 0 || !isLetter(rune(word[0])) {
+		return false
+	}
+	for _, r := range word {
+		if !isLetter(r) && !isDigit(r) {
+			return false
+		}
+	}
+	switch Keyword(word) {
+	case PACKAGE, FORALL, SAVE, PRINT, FUNC, RETURN, END:
+		return false
+	}
+	_, isType := dataTypes[word]
+	return !isType && word != "true" && word != "false"
+}
@@ -0,0 +1,59 @@
+package zscript
+
+import "fmt"
+
+// pushScope opens a new, narrower scope. Variables declared until the matching popScope belong to it.
+func (p *Parser) pushScope() {
+	p.current = append(p.current, []string{})
+	p.shadowed = append(p.shadowed, map[string]Object{})
+}
+
+// popScope deletes every variable declared in the narrowest scope and restores any variables they shadowed
+func (p *Parser) popScope() {
+	top := len(p.current) - 1
+	for _, name := range p.current[top] {
+		delete(p.objects, name)
+	}
+	for name, obj := range p.shadowed[top] {
+		p.objects[name] = obj
+	}
+	p.current = p.current[:top]
+	p.shadowed = p.shadowed[:top]
+}
+
+// declare creates a new variable in the narrowest scope. A variable of the same name in a wider scope is
+// shadowed until the narrowest scope is popped.
+func (p *Parser) declare(name string, obj Object) error {
+	top := len(p.current) - 1
+	for _, declared := range p.current[top] {
+		if declared == name {
+			return fmt.Errorf("%v is already declared in this scope", name)
+		}
+	}
+	if old, ok := p.objects[name]; ok {
+		p.shadowed[top][name] = old
+	}
+	p.current[top] = append(p.current[top], name)
+	p.objects[name] = obj
+	return nil
+}
+
+// assign sets an existing variable to a new value, converting the value to the variable's type if necessary
+func (p *Parser) assign(name string, obj Object) error {
+	old, ok := p.objects[name]
+	if !ok {
+		return fmt.Errorf("undefined: %v", name)
+	}
+	conv, err := convert(obj, old.Type())
+	if err != nil {
+		return err
+	}
+	p.objects[name] = conv
+	return nil
+}
+
+// lookup returns the variable of the desired name, if it exists in any scope
+func (p *Parser) lookup(name string) (Object, bool) {
+	obj, ok := p.objects[name]
+	return obj, ok
+}
@@ -59,5 +59,8 @@ func GetWords(line string) []string {
 			current += string(letter)
 		}
 	}
+	if len(current) != 0 {
+		words = append(words, current)
+	}
 	return words
 }